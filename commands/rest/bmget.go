@@ -1,8 +1,6 @@
 package rest
 
 import (
-	"net/http"
-
 	"github.com/brada954/restshell/shell"
 )
 
@@ -10,6 +8,7 @@ type BmGetCommand struct {
 	// Place getopt option value pointers here
 	optionUseHead   *bool
 	optionUseDelete *bool
+	optionMethod    *string
 	optionLabel     *string
 	// Processing variables
 	aborted bool
@@ -24,6 +23,7 @@ func (cmd *BmGetCommand) AddOptions(set shell.CmdSet) {
 	set.SetParameters("[service route]")
 	cmd.optionUseHead = set.BoolLong("head", 0, "Use HTTP HEAD method")
 	cmd.optionUseDelete = set.BoolLong("delete", 0, "Use HTTP DELETE method")
+	cmd.optionMethod = AddMethodOption(set)
 	cmd.optionLabel = set.StringLong("label", 0, "", "Label for results")
 	shell.AddCommonCmdOptions(set, shell.CmdDebug, shell.CmdVerbose, shell.CmdUrl, shell.CmdBasicAuth,
 		shell.CmdQueryParamAuth, shell.CmdRestclient, shell.CmdBenchmarks, shell.CmdTimeout)
@@ -44,11 +44,9 @@ func (cmd *BmGetCommand) Execute(args []string) error {
 		return shell.PushError(shell.ErrArguments)
 	}
 
-	method := http.MethodGet
-	if *cmd.optionUseHead {
-		method = http.MethodHead
-	} else if *cmd.optionUseDelete {
-		method = http.MethodDelete
+	method, err := GetMethodFromOptions(*cmd.optionMethod, *cmd.optionUseHead, *cmd.optionUseDelete)
+	if err != nil {
+		return err
 	}
 
 	if len(*cmd.optionLabel) == 0 {
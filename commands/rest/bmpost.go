@@ -1,8 +1,6 @@
 package rest
 
 import (
-	"strings"
-
 	"github.com/brada954/restshell/shell"
 )
 
@@ -47,7 +45,10 @@ func (cmd *BmPostCommand) Execute(args []string) error {
 		return shell.PushError(shell.ErrArguments)
 	}
 
-	method := cmd.postOptions.GetPostMethod()
+	method, err := cmd.postOptions.GetPostMethod()
+	if err != nil {
+		return err
+	}
 	if len(*cmd.optionLabel) == 0 {
 		*cmd.optionLabel = method
 	}
@@ -56,9 +57,8 @@ func (cmd *BmPostCommand) Execute(args []string) error {
 		return err
 	}
 
-	body := postBody.Content()
 	if *cmd.useSubstitution {
-		body = shell.PerformVariableSubstitution(body)
+		postBody = postBody.Substitute()
 	}
 
 	// Get an auth context
@@ -74,19 +74,13 @@ func (cmd *BmPostCommand) Execute(args []string) error {
 			rc = &tmprc
 		}
 
-		postdata := body
+		postdata := postBody
 		if *cmd.useSubstitutionPerIteration {
-			postdata = shell.PerformVariableSubstitution(postdata)
+			postdata = postdata.Substitute()
 		}
 
 		return func() (*shell.RestResponse, error) {
-			if strings.HasSuffix(postBody.ContentType(), "xml") {
-				return rc.DoWithXml(method, authContext, url, postdata)
-			} else if strings.HasSuffix(postBody.ContentType(), "json") {
-				return rc.DoWithJson(method, authContext, url, postdata)
-			} else {
-				return rc.DoWithForm(method, authContext, url, postdata)
-			}
+			return DoWithPostBody(rc, method, authContext, url, postdata.ContentType(), postdata.Content())
 		}
 	}
 
@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brada954/restshell/shell"
+)
+
+// CookiesCommand -- List and manage the cookie jar of the session
+type CookiesCommand struct {
+	optionClear  *bool
+	optionExport *string
+	optionImport *string
+	optionForce  *bool
+}
+
+func NewCookiesCommand() *CookiesCommand {
+	return &CookiesCommand{}
+}
+
+func (cmd *CookiesCommand) AddOptions(set shell.CmdSet) {
+	set.SetParameters("[domain]")
+	cmd.optionClear = set.BoolLong("clear", 0, "Remove the cookies from the jar")
+	cmd.optionExport = set.StringLong("export", 0, "", "Write the cookies to a Netscape cookies.txt file", "file")
+	cmd.optionImport = set.StringLong("import", 0, "", "Add the cookies of a Netscape cookies.txt file", "file")
+	cmd.optionForce = set.BoolLong("force", 'f', "Overwrite an existing export file")
+
+	set.SetUsage(func() {
+		cmd.HeaderUsage(shell.ConsoleWriter())
+		set.PrintUsage(shell.ConsoleWriter())
+	})
+
+	shell.AddCommonCmdOptions(set, shell.CmdDebug, shell.CmdVerbose, shell.CmdSilent)
+}
+
+func (cmd *CookiesCommand) HeaderUsage(w io.Writer) {
+	fmt.Fprintln(w, "COOKIES [domain]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "List the cookies of the session cookie jar. A domain limits the list, --clear and")
+	fmt.Fprintln(w, "--export to the cookies of the domain and its subdomains; --clear runs before")
+	fmt.Fprintln(w, "--import and --import before --export.")
+	fmt.Fprintln(w)
+}
+
+// Execute -- list, clear, import or export the cookies of the jar
+func (cmd *CookiesCommand) Execute(args []string) error {
+	if len(args) > 1 {
+		return shell.ErrArguments
+	}
+	domain := ""
+	if len(args) > 0 {
+		domain = args[0]
+	}
+
+	jar := shell.GetCookieJar()
+	list := true
+	if *cmd.optionClear {
+		count := jar.Clear(domain)
+		if !shell.IsCmdSilentEnabled() {
+			fmt.Fprintf(shell.OutputWriter(), "Removed %d cookies\n", count)
+		}
+		list = false
+	}
+
+	if len(*cmd.optionImport) > 0 {
+		file, err := os.Open(*cmd.optionImport)
+		if err != nil {
+			return fmt.Errorf("unable to open cookie file: %s", err.Error())
+		}
+		defer file.Close()
+		count, err := jar.Import(file)
+		if err != nil {
+			return fmt.Errorf("unable to import %s: %s", *cmd.optionImport, err.Error())
+		}
+		if !shell.IsCmdSilentEnabled() {
+			fmt.Fprintf(shell.OutputWriter(), "Imported %d cookies from %s\n", count, *cmd.optionImport)
+		}
+		list = false
+	}
+
+	if len(*cmd.optionExport) > 0 {
+		if err := cmd.export(jar, *cmd.optionExport, domain); err != nil {
+			return err
+		}
+		list = false
+	}
+
+	if list {
+		listCookies(shell.OutputWriter(), jar.List(domain))
+	}
+	return nil
+}
+
+func (cmd *CookiesCommand) export(jar *shell.CookieJar, name string, domain string) error {
+	if _, err := os.Stat(name); err == nil && !*cmd.optionForce {
+		return fmt.Errorf("file already exists: %s", name)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("unable to create cookie file: %s", err.Error())
+	}
+	count, err := jar.Export(file, domain)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to export cookies: %s", err.Error())
+	}
+	if !shell.IsCmdSilentEnabled() {
+		fmt.Fprintf(shell.OutputWriter(), "Exported %d cookies to %s\n", count, name)
+	}
+	return nil
+}
+
+// listCookies -- write a line for each cookie; domains starting with a dot
+// include their subdomains
+func listCookies(w io.Writer, cookies []shell.JarCookie) {
+	if len(cookies) == 0 {
+		fmt.Fprintln(w, "No cookies")
+		return
+	}
+
+	domainWidth, pathWidth := 0, 0
+	for _, c := range cookies {
+		if n := len(cookieDomain(c)); n > domainWidth {
+			domainWidth = n
+		}
+		if n := len(c.Path); n > pathWidth {
+			pathWidth = n
+		}
+	}
+
+	for _, c := range cookies {
+		attributes := make([]string, 0)
+		if c.IsSession() {
+			attributes = append(attributes, "session")
+		} else {
+			attributes = append(attributes, "expires "+c.Expires.Local().Format(time.RFC3339))
+		}
+		if c.Secure {
+			attributes = append(attributes, "secure")
+		}
+		if c.HttpOnly {
+			attributes = append(attributes, "httponly")
+		}
+		fmt.Fprintf(w, "%-*s  %-*s  %s=%s  (%s)\n", domainWidth, cookieDomain(c), pathWidth, c.Path,
+			c.Name, c.Value, strings.Join(attributes, ", "))
+	}
+}
+
+func cookieDomain(c shell.JarCookie) string {
+	if c.HostOnly {
+		return c.Domain
+	}
+	return "." + c.Domain
+}
@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brada954/restshell/shell"
+)
+
+// progressInterval -- the time between progress updates of a download
+const progressInterval = 500 * time.Millisecond
+
+// DownloadCommand -- Stream the response of a request to a file
+type DownloadCommand struct {
+	optionForce      *bool
+	optionNoProgress *bool
+	optionMethod     *string
+	// Processing variables
+	aborted bool
+}
+
+func NewDownloadCommand() *DownloadCommand {
+	return &DownloadCommand{}
+}
+
+func (cmd *DownloadCommand) AddOptions(set shell.CmdSet) {
+	set.SetParameters("[service route] [file]")
+	cmd.optionForce = set.BoolLong("force", 'f', "Overwrite an existing file")
+	cmd.optionNoProgress = set.BoolLong("no-progress", 0, "Do not display the progress of the download")
+	cmd.optionMethod = AddMethodOption(set)
+	shell.AddCommonCmdOptions(set, shell.CmdDebug, shell.CmdVerbose, shell.CmdSilent, shell.CmdUrl, shell.CmdBasicAuth,
+		shell.CmdQueryParamAuth, shell.CmdRestclient, shell.CmdTimeout)
+}
+
+// Execute -- Download the response of a route to a file; the file defaults
+// to the last segment of the URL path
+func (cmd *DownloadCommand) Execute(args []string) error {
+	cmd.aborted = false
+	if len(args) > 2 {
+		return shell.ErrArguments
+	}
+
+	// Determine route
+	route := ""
+	if len(args) > 0 {
+		route = args[0]
+	}
+
+	// Build URL
+	url := shell.GetCmdUrlValue(GenerateBaseUrl(route))
+	if url == "" {
+		return shell.PushError(shell.ErrArguments)
+	}
+
+	file := getDownloadFileName(url)
+	if len(args) > 1 {
+		if stat, err := os.Stat(args[1]); err == nil && stat.IsDir() {
+			file = filepath.Join(args[1], file)
+		} else {
+			file = args[1]
+		}
+	}
+	if len(file) == 0 || strings.HasSuffix(file, string(filepath.Separator)) {
+		return errors.New("a file is required when the URL does not end with a file name")
+	}
+	if _, err := os.Stat(file); err == nil && !*cmd.optionForce {
+		return fmt.Errorf("file already exists: %s", file)
+	}
+
+	method, err := GetMethodFromOptions(*cmd.optionMethod, false, false)
+	if err != nil {
+		return err
+	}
+
+	// Get an auth context
+	var authContext = shell.GetCmdBasicAuthContext(shell.GetCmdQueryParamAuthContext(GetBaseAuthContext()))
+
+	// The content is written to a temporary file renamed when complete
+	var temp *os.File
+	defer func() {
+		if temp != nil {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	hasher := shell.NewContentHasher()
+	var progress *downloadProgress
+	client := shell.NewRestClientFromOptions()
+	resp, _, err := client.DoMethodToWriter(method, authContext, url, func(resp *http.Response) (io.Writer, error) {
+		var err error
+		temp, err = os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+		if err != nil {
+			return nil, err
+		}
+		progress = &downloadProgress{cmd: cmd, total: resp.ContentLength, hasher: hasher, shown: -1}
+		progress.show = !*cmd.optionNoProgress && !shell.IsCmdSilentEnabled()
+		return io.MultiWriter(temp, hasher, progress), nil
+	})
+	if progress != nil {
+		progress.done()
+	}
+	if err != nil || temp == nil {
+		return shell.DownloadCompletionHandler(resp, nil, err)
+	}
+
+	if err := temp.Chmod(0644); err != nil {
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), file); err != nil {
+		return err
+	}
+	temp = nil
+
+	info := hasher.Info()
+	info.File = file
+	return shell.DownloadCompletionHandler(resp, &info, nil)
+}
+
+func (cmd *DownloadCommand) Abort() {
+	cmd.aborted = true
+}
+
+// getDownloadFileName -- the last segment of the path of a URL
+func getDownloadFileName(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// downloadProgress -- a writer displaying the progress of a download and
+// stopping it when the command is aborted
+type downloadProgress struct {
+	cmd    *DownloadCommand
+	hasher *shell.ContentHasher
+	total  int64
+	show   bool
+	shown  int64 // Size of the last progress displayed (-1 before the first)
+	last   time.Time
+}
+
+func (p *downloadProgress) Write(b []byte) (int, error) {
+	if p.cmd.aborted {
+		return 0, errors.New("download canceled")
+	}
+	if p.show && time.Since(p.last) >= progressInterval {
+		p.last = time.Now()
+		p.display()
+	}
+	return len(b), nil
+}
+
+func (p *downloadProgress) display() {
+	size := p.hasher.Size()
+	p.shown = size
+	if p.total > 0 {
+		fmt.Fprintf(shell.ConsoleWriter(), "\rDownloaded %d of %d bytes (%d%%)", size, p.total, size*100/p.total)
+	} else {
+		fmt.Fprintf(shell.ConsoleWriter(), "\rDownloaded %d bytes", size)
+	}
+}
+
+// done -- complete the progress line
+func (p *downloadProgress) done() {
+	if p.shown >= 0 {
+		if p.shown != p.hasher.Size() {
+			p.display()
+		}
+		fmt.Fprintln(shell.ConsoleWriter())
+	}
+}
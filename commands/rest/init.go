@@ -10,9 +10,12 @@ func AddCommands() {
 	shell.AddCommand("base", shell.CategoryHttp, NewBaseCommand())
 	shell.AddCommand("get", shell.CategoryHttp, NewGetCommand())
 	shell.AddCommand("post", shell.CategoryHttp, NewPostCommand())
+	shell.AddCommand("request", shell.CategoryHttp, NewRequestCommand())
+	shell.AddCommand("download", shell.CategoryHttp, NewDownloadCommand())
 	shell.AddCommand("bmget", shell.CategoryBenchmarks, NewBmGetCommand())
 	shell.AddCommand("bmpost", shell.CategoryBenchmarks, NewBmPostCommand())
 	shell.AddCommand("smget", shell.CategoryBenchmarks, NewSmGetCommand())
 	shell.AddCommand("smpost", shell.CategoryBenchmarks, NewSmPostCommand())
 	shell.AddCommand("login", shell.CategoryHttp, NewLoginCommand())
+	shell.AddCommand("cookies", shell.CategoryHttp, NewCookiesCommand())
 }
@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+
+	"github.com/brada954/restshell/shell"
+)
+
+const DefaultPartContentType = "application/octet-stream"
+
+// filePart -- a file of a multipart body parsed from name=@path[;type=mime]
+type filePart struct {
+	name        string
+	file        string
+	contentType string
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// parseTextPart -- parse a text field of the form name=value
+func parseTextPart(option string) (string, string, error) {
+	name, value, ok := strings.Cut(option, "=")
+	if !ok || len(name) == 0 {
+		return "", "", fmt.Errorf("invalid part (expected name=value): %s", option)
+	}
+	return name, value, nil
+}
+
+// parseFilePart -- parse a file part of the form name=@path[;type=mime]; the
+// content type is guessed from the file extension when not given
+func parseFilePart(option string) (*filePart, error) {
+	name, value, ok := strings.Cut(option, "=")
+	if !ok || len(name) == 0 {
+		return nil, fmt.Errorf("invalid file part (expected name=@path): %s", option)
+	}
+
+	part := &filePart{name: name}
+	fields := strings.Split(strings.TrimPrefix(value, "@"), ";")
+	part.file = fields[0]
+	for _, field := range fields[1:] {
+		key, param, _ := strings.Cut(field, "=")
+		if strings.EqualFold(strings.TrimSpace(key), "type") && len(strings.TrimSpace(param)) > 0 {
+			part.contentType = strings.TrimSpace(param)
+		} else {
+			return nil, fmt.Errorf("invalid file part parameter: %s", field)
+		}
+	}
+
+	if len(part.file) == 0 {
+		return nil, fmt.Errorf("invalid file part (missing file): %s", option)
+	}
+	if len(part.contentType) == 0 {
+		part.contentType = GuessContentType(part.file)
+	}
+	return part, nil
+}
+
+// GuessContentType -- the MIME type of a file based on its extension
+func GuessContentType(file string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(file)); len(contentType) > 0 {
+		return contentType
+	}
+	return DefaultPartContentType
+}
+
+// multipartForm -- the text fields and files of a multipart body; the body is
+// built again when the variables of the text fields are substituted
+type multipartForm struct {
+	fields   [][2]string
+	files    []*filePart
+	contents [][]byte
+	boundary string
+}
+
+// NewMultipartBody -- build a multipart/form-data body of the text fields
+// followed by the files
+func NewMultipartBody(parts []string, fileParts []string) (*PostBody, error) {
+	form := &multipartForm{boundary: multipart.NewWriter(nil).Boundary()}
+	for _, option := range parts {
+		name, value, err := parseTextPart(option)
+		if err != nil {
+			return nil, err
+		}
+		form.fields = append(form.fields, [2]string{name, value})
+	}
+
+	for _, option := range fileParts {
+		part, err := parseFilePart(option)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := shell.GetBinaryFileContents(part.file)
+		if err != nil {
+			return nil, errors.New("unable to read file part: " + err.Error())
+		}
+		form.files = append(form.files, part)
+		form.contents = append(form.contents, content)
+	}
+	return form.body(), nil
+}
+
+// body -- the post body of the form; writing to a buffer does not fail
+func (f *multipartForm) body() *PostBody {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+	writer.SetBoundary(f.boundary)
+
+	for _, field := range f.fields {
+		writer.WriteField(field[0], field[1])
+	}
+
+	for i, part := range f.files {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(part.name), quoteEscaper.Replace(filepath.Base(part.file))))
+		header.Set("Content-Type", part.contentType)
+		w, _ := writer.CreatePart(header)
+		w.Write(f.contents[i])
+	}
+
+	writer.Close()
+	return &PostBody{body: buffer.String(), contentType: writer.FormDataContentType(), form: f}
+}
+
+// substitute -- a form with the variables of the text fields substituted
+func (f *multipartForm) substitute() *multipartForm {
+	form := *f
+	form.fields = make([][2]string, len(f.fields))
+	for i, field := range f.fields {
+		form.fields[i] = [2]string{field[0], shell.PerformVariableSubstitution(field[1])}
+	}
+	return &form
+}
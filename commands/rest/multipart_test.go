@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brada954/restshell/shell"
+)
+
+func TestParseFilePart(t *testing.T) {
+	tests := []struct {
+		option      string
+		name        string
+		file        string
+		contentType string
+		valid       bool
+	}{
+		{"doc=@report.pdf", "doc", "report.pdf", "application/pdf", true},
+		{"doc=report.pdf", "doc", "report.pdf", "application/pdf", true},
+		{"img=@photo.png;type=image/x-custom", "img", "photo.png", "image/x-custom", true},
+		{"img=@photo.png; TYPE = image/jpeg", "img", "photo.png", "image/jpeg", true},
+		{"data=@dir/data.json", "data", "dir/data.json", "application/json", true},
+		{"blob=@archive.unknownext", "blob", "archive.unknownext", DefaultPartContentType, true},
+		{"blob=@noextension", "blob", "noextension", DefaultPartContentType, true},
+		{"=@file.txt", "", "", "", false},
+		{"file.txt", "", "", "", false},
+		{"doc=@", "", "", "", false},
+		{"doc=@file.txt;type=", "", "", "", false},
+		{"doc=@file.txt;charset=utf-8", "", "", "", false},
+	}
+
+	for _, test := range tests {
+		part, err := parseFilePart(test.option)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("expected an error for %q but got %+v", test.option, part)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", test.option, err.Error())
+			continue
+		}
+		contentType, _, _ := strings.Cut(part.contentType, ";")
+		if part.name != test.name || part.file != test.file || contentType != test.contentType {
+			t.Errorf("expected %s %s %s for %q but got %+v", test.name, test.file, test.contentType, test.option, part)
+		}
+	}
+}
+
+// readMultipartBody -- the parts of a multipart body as "name|filename|type|content"
+func readMultipartBody(t *testing.T, body *PostBody) []string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(body.ContentType())
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("unexpected content type: %s", body.ContentType())
+	}
+
+	parts := make([]string, 0)
+	reader := multipart.NewReader(strings.NewReader(body.Content()), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		} else if err != nil {
+			t.Fatalf("invalid multipart body: %s", err.Error())
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, strings.Join([]string{part.FormName(), part.FileName(),
+			part.Header.Get("Content-Type"), string(content)}, "|"))
+	}
+}
+
+func TestNewMultipartBody(t *testing.T) {
+	dir := t.TempDir()
+	binary := string([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff, '%', '%', 'x', '%', '%'})
+	os.WriteFile(filepath.Join(dir, "image.png"), []byte(binary), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes %%name%%"), 0644)
+
+	tests := []struct {
+		title     string
+		parts     []string
+		fileParts []string
+		expected  []string
+		valid     bool
+	}{
+		{"fields", []string{"name=value", "empty=", "eq=a=b"}, nil,
+			[]string{"name|||value", "empty|||", "eq|||a=b"}, true},
+		{"files", []string{"title=pics"}, []string{"img=@" + filepath.Join(dir, "image.png"),
+			"doc=@" + filepath.Join(dir, "notes.txt") + ";type=text/markdown"},
+			[]string{"title|||pics", "img|image.png|image/png|" + binary, "doc|notes.txt|text/markdown|notes %%name%%"}, true},
+		{"missing file", nil, []string{"doc=@" + filepath.Join(dir, "missing.txt")}, nil, false},
+		{"invalid field", []string{"novalue"}, nil, nil, false},
+	}
+
+	for _, test := range tests {
+		body, err := NewMultipartBody(test.parts, test.fileParts)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.title, err.Error())
+			continue
+		}
+		parts := readMultipartBody(t, body)
+		if strings.Join(parts, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%s: expected parts %q but got %q", test.title, test.expected, parts)
+		}
+	}
+}
+
+func TestMultipartBodySubstitutesFieldsOnly(t *testing.T) {
+	shell.SetGlobal("name", "widget")
+	defer shell.RemoveGlobal("name")
+
+	file := filepath.Join(t.TempDir(), "data.bin")
+	os.WriteFile(file, []byte("raw %%name%% \x00\xff"), 0644)
+
+	body, err := NewMultipartBody([]string{"title=%%name%%", "other=%%unknown%%"}, []string{"file=@" + file})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if body.IsSubstitutionComplete() {
+		t.Errorf("expected the fields to have variables to substitute")
+	}
+
+	substituted := body.Substitute()
+	parts := readMultipartBody(t, substituted)
+	if len(parts) != 3 || !strings.HasSuffix(parts[0], "|widget") || !strings.HasSuffix(parts[1], "|%%unknown%%") ||
+		parts[2] != "file|data.bin|application/octet-stream|raw %%name%% \x00\xff" {
+		t.Errorf("expected only the fields to be substituted: %q", parts)
+	}
+	if substituted.ContentType() != body.ContentType() || substituted.IsSubstitutionComplete() {
+		t.Errorf("expected the same boundary and an unknown variable left")
+	}
+
+	plain := (&PostBody{body: `{"name":"%%name%%"}`, contentType: "application/json"}).Substitute()
+	if plain.Content() != `{"name":"widget"}` || !plain.IsSubstitutionComplete() {
+		t.Errorf("expected the whole body to be substituted: %s", plain.Content())
+	}
+}
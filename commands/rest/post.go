@@ -3,7 +3,6 @@ package rest
 import (
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/brada954/restshell/shell"
 )
@@ -45,31 +44,25 @@ func (cmd *PostCommand) Execute(args []string) error {
 	// Get an auth context
 	cmd.useAuthContext = shell.GetCmdBasicAuthContext(shell.GetCmdQueryParamAuthContext(GetBaseAuthContext()))
 
-	method := cmd.postOptions.GetPostMethod()
+	method, err := cmd.postOptions.GetPostMethod()
+	if err != nil {
+		return err
+	}
 	postBody, err := cmd.postOptions.GetPostBody()
 	if err != nil {
 		return shell.PushError(err)
 	}
 
-	body := postBody.Content()
 	if *cmd.useSubstitution {
-		body = shell.PerformVariableSubstitution(body)
+		postBody = postBody.Substitute()
 	}
 
-	if shell.IsVariableSubstitutionComplete(body) == false {
+	if postBody.IsSubstitutionComplete() == false {
 		fmt.Fprintf(shell.ErrorWriter(), "WARNING: post body contains unsubstituted variables")
 	}
 
 	// Execute commands
 	client := shell.NewRestClientFromOptions()
-	if strings.HasSuffix(postBody.ContentType(), "xml") {
-		resp, err := client.DoWithXml(method, cmd.useAuthContext, url, body)
-		return shell.RestCompletionHandler(resp, err, nil)
-	} else if strings.HasSuffix(postBody.ContentType(), "json") {
-		resp, err := client.DoWithJson(method, cmd.useAuthContext, url, body)
-		return shell.RestCompletionHandler(resp, err, nil)
-	} else {
-		resp, err := client.DoWithForm(method, cmd.useAuthContext, url, body)
-		return shell.RestCompletionHandler(resp, err, nil)
-	}
+	resp, err := DoWithPostBody(&client, method, cmd.useAuthContext, url, postBody.ContentType(), postBody.Content())
+	return shell.RestCompletionHandler(resp, err, nil)
 }
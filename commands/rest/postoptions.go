@@ -2,7 +2,9 @@ package rest
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/brada954/restshell/shell"
@@ -17,11 +19,13 @@ const (
 	DefaultXMLFile  = ""
 	DefaultFormVar  = ""
 	DefaultBodyFile = ""
+	DefaultMethod   = ""
 )
 
 type PostOptions struct {
 	optionUsePut     *bool
 	optionUseOption  *bool
+	optionMethod     *string
 	optionJsonVar    *string
 	optionJson       *string
 	optionJsonFile   *string
@@ -31,11 +35,14 @@ type PostOptions struct {
 	optionFormVar    *string
 	optionBodyFile   *string
 	optionLastResult *bool
+	optionParts      *shell.StringList
+	optionFileParts  *shell.StringList
 }
 
 type PostBody struct {
 	body        string
 	contentType string
+	form        *multipartForm // The fields and files of a multipart body
 }
 
 // Body -- the body of a post
@@ -54,11 +61,45 @@ func (pb *PostBody) ContentType() string {
 	return pb.contentType
 }
 
-// AddModifierOptions -- Add options for modifiers
+// Substitute -- the body with variables substituted; only the text fields
+// of a multipart body are substituted so the files are sent unchanged
+func (pb *PostBody) Substitute() *PostBody {
+	if pb == nil {
+		return nil
+	} else if pb.form != nil {
+		return pb.form.substitute().body()
+	}
+	return &PostBody{body: shell.PerformVariableSubstitution(pb.body), contentType: pb.contentType}
+}
+
+// IsSubstitutionComplete -- true if the body (or the text fields of a
+// multipart body) has no variables left to substitute
+func (pb *PostBody) IsSubstitutionComplete() bool {
+	if pb == nil {
+		return true
+	} else if pb.form != nil {
+		for _, field := range pb.form.fields {
+			if !shell.IsVariableSubstitutionComplete(field[1]) {
+				return false
+			}
+		}
+		return true
+	}
+	return shell.IsVariableSubstitutionComplete(pb.body)
+}
+
+// AddPostOptions -- Add options for the method and body of a post
 func AddPostOptions(set shell.CmdSet) PostOptions {
-	options := PostOptions{}
+	options := AddBodyOptions(set)
 	options.optionUsePut = set.BoolLong("put", 0, "Use PUT method instead of post")
 	options.optionUseOption = set.BoolLong("options", 0, "Use OPTIONS method instead of post")
+	options.optionMethod = AddMethodOption(set)
+	return options
+}
+
+// AddBodyOptions -- Add options for the sources of a request body
+func AddBodyOptions(set shell.CmdSet) PostOptions {
+	options := PostOptions{}
 	options.optionJsonVar = set.StringLong("json-var", 0, DefaultJsonVar, "Use a named variable as body of json request", "name")
 	options.optionJson = set.StringLong("json", 0, DefaultJsonBody, "Send the given json in the body", "json")
 	options.optionForm = set.StringLong("form", 0, DefaultFormBody, "Send the given form body", "form")
@@ -68,6 +109,8 @@ func AddPostOptions(set shell.CmdSet) PostOptions {
 	options.optionXMLFile = set.StringLong("xml-file", 0, DefaultXMLFile, "Use the given file for xml request", "file")
 	options.optionBodyFile = set.StringLong("body", 0, DefaultBodyFile, "Send the given file in the body", "file")
 	options.optionLastResult = set.BoolLong("result", 0, "Use last result in post body")
+	options.optionParts = set.StringListLong("part", 0, "Add a text field to a multipart body", "name=value")
+	options.optionFileParts = set.StringListLong("file-part", 0, "Add a file to a multipart body", "name=@file[;type=mime]")
 	return options
 }
 
@@ -79,6 +122,15 @@ func getPostBodyFromFile(filename, extension, contentType string) (*PostBody, er
 	return &PostBody{body: body, contentType: contentType}, nil
 }
 
+// HasPostBody -- true if an option provides a body
+func (p *PostOptions) HasPostBody() bool {
+	return *p.optionJson != DefaultJsonBody || *p.optionJsonVar != DefaultJsonVar ||
+		*p.optionXMLVar != DefaultXMLVar || *p.optionForm != DefaultFormBody ||
+		*p.optionJsonFile != DefaultJsonFile || *p.optionXMLFile != DefaultXMLFile ||
+		*p.optionBodyFile != DefaultBodyFile || *p.optionFormVar != DefaultFormVar || *p.optionLastResult ||
+		p.optionParts.Count() > 0 || p.optionFileParts.Count() > 0
+}
+
 // GetPostBody -- Get a post body based on post options
 func (p *PostOptions) GetPostBody() (*PostBody, error) {
 
@@ -104,17 +156,63 @@ func (p *PostOptions) GetPostBody() (*PostBody, error) {
 			return nil, err
 		}
 		return &PostBody{body: r.Text, contentType: strings.ToLower(r.ContentType)}, nil
+	} else if p.optionParts.Count() > 0 || p.optionFileParts.Count() > 0 {
+		return NewMultipartBody(p.optionParts.GetValues(), p.optionFileParts.GetValues())
 	}
 	return nil, errors.New("No post body provided")
 }
 
 // GetPostMethod -- Returns the configured HTTP method
-func (p *PostOptions) GetPostMethod() string {
+func (p *PostOptions) GetPostMethod() (string, error) {
 	method := http.MethodPost
-	if *p.optionUsePut {
+	if p.optionMethod != nil && *p.optionMethod != DefaultMethod {
+		return ValidateMethod(*p.optionMethod)
+	} else if p.optionUsePut != nil && *p.optionUsePut {
 		method = http.MethodPut
-	} else if *p.optionUseOption {
+	} else if p.optionUseOption != nil && *p.optionUseOption {
 		method = http.MethodOptions
 	}
-	return method
+	return method, nil
+}
+
+// DoWithPostBody -- Perform a request sending the body with the client method
+// for the content type of the body
+func DoWithPostBody(client *shell.RestClient, method string, authContext shell.Auth, url string, contentType string, body string) (*shell.RestResponse, error) {
+	if strings.HasPrefix(contentType, "multipart/") {
+		return client.DoMethodWithBody(method, authContext, url, contentType, body)
+	} else if strings.HasSuffix(contentType, "xml") {
+		return client.DoWithXml(method, authContext, url, body)
+	} else if strings.HasSuffix(contentType, "json") {
+		return client.DoWithJson(method, authContext, url, body)
+	} else {
+		return client.DoWithForm(method, authContext, url, body)
+	}
+}
+
+// AddMethodOption -- Add an option for any HTTP method (e.g. PATCH)
+func AddMethodOption(set shell.CmdSet) *string {
+	return set.StringLong("method", 0, DefaultMethod, "Use the given HTTP method (e.g. PATCH or PURGE)", "method")
+}
+
+var methodPattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// ValidateMethod -- Validate an HTTP method name and return it in upper case
+func ValidateMethod(method string) (string, error) {
+	if !methodPattern.MatchString(method) {
+		return "", fmt.Errorf("invalid HTTP method: %s", method)
+	}
+	return strings.ToUpper(method), nil
+}
+
+// GetMethodFromOptions -- Returns the HTTP method of commands without a body;
+// the method option overrides the HEAD and DELETE options
+func GetMethodFromOptions(method string, useHead bool, useDelete bool) (string, error) {
+	if method != DefaultMethod {
+		return ValidateMethod(method)
+	} else if useHead {
+		return http.MethodHead, nil
+	} else if useDelete {
+		return http.MethodDelete, nil
+	}
+	return http.MethodGet, nil
 }
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValidateMethod(t *testing.T) {
+	tests := []struct {
+		method   string
+		expected string
+		valid    bool
+	}{
+		{"GET", "GET", true},
+		{"patch", "PATCH", true},
+		{"Purge", "PURGE", true},
+		{"m-search", "M-SEARCH", true},
+		{"", "", false},
+		{"GET /books", "", false},
+		{"POST\n", "", false},
+		{"GET:", "", false},
+	}
+
+	for _, test := range tests {
+		method, err := ValidateMethod(test.method)
+		if test.valid && (err != nil || method != test.expected) {
+			t.Errorf("expected %q for %q but got %q (%v)", test.expected, test.method, method, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("expected an error for %q but got %q", test.method, method)
+		}
+	}
+}
+
+func TestGetMethodFromOptions(t *testing.T) {
+	tests := []struct {
+		method    string
+		useHead   bool
+		useDelete bool
+		expected  string
+		valid     bool
+	}{
+		{DefaultMethod, false, false, http.MethodGet, true},
+		{DefaultMethod, true, false, http.MethodHead, true},
+		{DefaultMethod, false, true, http.MethodDelete, true},
+		{DefaultMethod, true, true, http.MethodHead, true}, // HEAD like the GET command
+		{"patch", false, false, http.MethodPatch, true},
+		{"purge", true, false, "PURGE", true}, // The method overrides --head
+		{"options", false, true, http.MethodOptions, true},
+		{"bad method", true, true, "", false},
+	}
+
+	for _, test := range tests {
+		method, err := GetMethodFromOptions(test.method, test.useHead, test.useDelete)
+		if test.valid && (err != nil || method != test.expected) {
+			t.Errorf("expected %q for %+v but got %q (%v)", test.expected, test, method, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("expected an error for %+v but got %q", test, method)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brada954/restshell/shell"
+)
+
+// RequestCommand -- Perform a request with any HTTP method and an optional body
+type RequestCommand struct {
+	useSubstitution *bool
+	postOptions     PostOptions
+}
+
+func NewRequestCommand() *RequestCommand {
+	return &RequestCommand{}
+}
+
+func (cmd *RequestCommand) AddOptions(set shell.CmdSet) {
+	set.SetParameters("method [service route]")
+
+	cmd.postOptions = AddBodyOptions(set)
+	cmd.useSubstitution = set.BoolLong("subst", 0, "Perform variable substitution")
+	shell.AddCommonCmdOptions(set, shell.CmdDebug, shell.CmdVerbose, shell.CmdSilent, shell.CmdUrl,
+		shell.CmdBasicAuth, shell.CmdQueryParamAuth, shell.CmdRestclient, shell.CmdFormatOutput, shell.CmdTimeout)
+}
+
+// Execute -- Execute the request with the method of the first argument
+func (cmd *RequestCommand) Execute(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return shell.ErrArguments
+	}
+
+	method, err := ValidateMethod(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Determine route
+	route := ""
+	if len(args) > 1 {
+		route = args[1]
+	}
+
+	// Build URL
+	url := shell.GetCmdUrlValue(GenerateBaseUrl(route))
+	if url == "" {
+		return shell.PushError(errors.New("unable to construct URL"))
+	}
+
+	// Get an auth context
+	authContext := shell.GetCmdBasicAuthContext(shell.GetCmdQueryParamAuthContext(GetBaseAuthContext()))
+
+	client := shell.NewRestClientFromOptions()
+	if !cmd.postOptions.HasPostBody() {
+		resp, err := client.DoMethod(method, authContext, url)
+		return shell.RestCompletionHandler(resp, err, nil)
+	}
+
+	postBody, err := cmd.postOptions.GetPostBody()
+	if err != nil {
+		return shell.PushError(err)
+	}
+
+	if *cmd.useSubstitution {
+		postBody = postBody.Substitute()
+	}
+
+	if postBody.IsSubstitutionComplete() == false {
+		fmt.Fprintf(shell.ErrorWriter(), "WARNING: request body contains unsubstituted variables")
+	}
+
+	resp, err := DoWithPostBody(&client, method, authContext, url, postBody.ContentType(), postBody.Content())
+	return shell.RestCompletionHandler(resp, err, nil)
+}
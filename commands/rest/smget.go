@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/brada954/restshell/shell"
@@ -11,6 +10,7 @@ type SmGetCommand struct {
 	// Place getopt option value pointers here
 	optionUseHead        *bool
 	optionUseDelete      *bool
+	optionMethod         *string
 	optionBuckets        *int
 	optionExpectedStatus *int
 	// Processing variables
@@ -25,6 +25,7 @@ func (cmd *SmGetCommand) AddOptions(set shell.CmdSet) {
 	set.SetParameters("[service route]")
 	cmd.optionUseHead = set.BoolLong("head", 0, "Use HTTP HEAD method")
 	cmd.optionUseDelete = set.BoolLong("delete", 0, "Use HTTP DELETE method")
+	cmd.optionMethod = AddMethodOption(set)
 	cmd.optionBuckets = set.IntLong("buckets", 'b', 10, "Time slice buckets for metric collection")
 	cmd.optionExpectedStatus = set.IntLong("expect-status", 0, 200, "Expected status from post [default=200]")
 	shell.AddCommonCmdOptions(set, shell.CmdDebug, shell.CmdVerbose, shell.CmdUrl, shell.CmdBasicAuth,
@@ -46,11 +47,9 @@ func (cmd *SmGetCommand) Execute(args []string) error {
 		return shell.PushError(shell.ErrArguments)
 	}
 
-	method := http.MethodGet
-	if *cmd.optionUseHead {
-		method = http.MethodHead
-	} else if *cmd.optionUseDelete {
-		method = http.MethodDelete
+	method, err := GetMethodFromOptions(*cmd.optionMethod, *cmd.optionUseHead, *cmd.optionUseDelete)
+	if err != nil {
+		return err
 	}
 
 	if *cmd.optionBuckets <= 0 || *cmd.optionBuckets >= 1000000 {
@@ -1,7 +1,6 @@
 package rest
 
 import (
-	"strings"
 	"time"
 
 	"github.com/brada954/restshell/shell"
@@ -46,15 +45,17 @@ func (cmd *SmPostCommand) Execute(args []string) error {
 		return shell.PushError(shell.ErrArguments)
 	}
 
-	method := cmd.postOptions.GetPostMethod()
+	method, err := cmd.postOptions.GetPostMethod()
+	if err != nil {
+		return err
+	}
 	postBody, err := cmd.postOptions.GetPostBody()
 	if err != nil {
 		return err
 	}
 
-	body := postBody.Content()
 	if *cmd.useSubstitution {
-		body = shell.PerformVariableSubstitution(body)
+		postBody = postBody.Substitute()
 	}
 
 	// Get an auth context
@@ -70,19 +71,13 @@ func (cmd *SmPostCommand) Execute(args []string) error {
 			rc = &tmprc
 		}
 
-		postdata := body
+		postdata := postBody
 		if *cmd.useSubstitutionPerIteration {
-			postdata = shell.PerformVariableSubstitution(postdata)
+			postdata = postdata.Substitute()
 		}
 
 		return func() (*shell.RestResponse, error) {
-			if strings.HasSuffix(postBody.ContentType(), "xml") {
-				return rc.DoWithXml(method, authContext, url, postdata)
-			} else if strings.HasSuffix(postBody.ContentType(), "json") {
-				return rc.DoWithJson(method, authContext, url, postdata)
-			} else {
-				return rc.DoWithForm(method, authContext, url, postdata)
-			}
+			return DoWithPostBody(rc, method, authContext, url, postdata.ContentType(), postdata.Content())
 		}
 	}
 
@@ -243,9 +243,9 @@ func (cmd *AssertCommand) returnError(io io.Writer, a Assert) error {
 	}
 
 	if *cmd.exitOption {
-		return shell.NewFlowError(sb.String(), shell.FlowAbort)
+		return shell.NewExitCodeError(shell.NewFlowError(sb.String(), shell.FlowAbort), shell.ExitAssertionFailed)
 	}
-	return errors.New(sb.String())
+	return shell.NewExitCodeError(errors.New(sb.String()), shell.ExitAssertionFailed)
 }
 
 func (cmd *AssertCommand) executeReporting() error {
@@ -281,6 +281,9 @@ func (cmd *AssertCommand) executeReporting() error {
 			if cmd.totalFailures > 0 && *cmd.exitOption {
 				reterr = shell.NewFlowError(reterr.Error(), shell.FlowAbort)
 			}
+			if cmd.totalFailures > 0 {
+				reterr = shell.NewExitCodeError(reterr, shell.ExitAssertionFailed)
+			}
 		} else if cmd.totalFailures == 0 && cmd.totalExecuted > 0 {
 			fmt.Fprintf(shell.OutputWriter(), "ALL ASSERTIONS PASSED (%d)\n", cmd.totalExecuted)
 		}
@@ -487,7 +490,7 @@ func (cmd *AssertCommand) buildFailedSummaryMessage() string {
 }
 
 func isEqual(i interface{}, value string) error {
-	comp, err := compare(i, value)
+	comp, err := shell.CompareNodeValue(i, value)
 	if err != nil {
 		return err
 	}
@@ -498,7 +501,7 @@ func isEqual(i interface{}, value string) error {
 }
 
 func isNotEqual(i interface{}, value string) error {
-	comp, err := compare(i, value)
+	comp, err := shell.CompareNodeValue(i, value)
 	if err != nil {
 		return err
 	}
@@ -509,7 +512,7 @@ func isNotEqual(i interface{}, value string) error {
 }
 
 func isGt(i interface{}, value string) error {
-	comp, err := compare(i, value)
+	comp, err := shell.CompareNodeValue(i, value)
 	if err != nil {
 		return err
 	}
@@ -520,7 +523,7 @@ func isGt(i interface{}, value string) error {
 }
 
 func isGte(i interface{}, value string) error {
-	comp, err := compare(i, value)
+	comp, err := shell.CompareNodeValue(i, value)
 	if err != nil {
 		return err
 	}
@@ -531,7 +534,7 @@ func isGte(i interface{}, value string) error {
 }
 
 func isLt(i interface{}, value string) error {
-	comp, err := compare(i, value)
+	comp, err := shell.CompareNodeValue(i, value)
 	if err != nil {
 		return err
 	}
@@ -542,7 +545,7 @@ func isLt(i interface{}, value string) error {
 }
 
 func isLte(i interface{}, value string) error {
-	comp, err := compare(i, value)
+	comp, err := shell.CompareNodeValue(i, value)
 	if err != nil {
 		return err
 	}
@@ -603,70 +606,6 @@ func isRegexMatch(i interface{}, pattern string) error {
 	return nil
 }
 
-func compare(i interface{}, value string) (int, error) {
-	if i == nil {
-		return +1, nil
-	}
-
-	switch t := i.(type) {
-	case string:
-		return strings.Compare(t, value), nil
-	case float64:
-		numValue, err := strconv.ParseFloat(value, 64)
-		if shell.IsCmdDebugEnabled() {
-			fmt.Fprintf(shell.ConsoleWriter(), "Debug: nodeValue: %f value: %f\n", t, numValue)
-		}
-		if err != nil {
-			return 0, shell.ErrInvalidValue
-		}
-		if math.Abs((t - numValue)) < .00001 {
-			return 0, nil
-		} else if t > numValue {
-			return +1, nil
-		} else {
-			return -1, nil
-		}
-	case int:
-		numValue, err := strconv.Atoi(value)
-		if err != nil {
-			return 0, err
-		}
-		if t == numValue {
-			return 0, nil
-		} else if t > numValue {
-			return +1, nil
-		} else {
-			return -1, nil
-		}
-	case int64:
-		numValue, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			return 0, err
-		}
-		if t == numValue {
-			return 0, nil
-		} else if t > numValue {
-			return +1, nil
-		} else {
-			return -1, nil
-		}
-	case bool:
-		boolval, err := strconv.ParseBool(value)
-		if err != nil {
-			return -1, err
-		}
-		if t == boolval {
-			return 0, nil
-		} else if boolval == false {
-			return +1, nil
-		} else {
-			return -1, nil
-		}
-	default:
-		return 0, errors.New(shell.ErrUnexpectedType.Error() + ": " + reflect.TypeOf(i).String())
-	}
-}
-
 func isNil(i interface{}) error {
 	if i == nil {
 		return nil
@@ -20,4 +20,5 @@ func AddCommands() {
 	shell.AddCommand("env", shell.CategoryUtilities, NewEnvCommand())
 	shell.AddCommand("sleep", shell.CategoryUtilities, NewSleepCommand())
 	shell.AddCommand("pause", shell.CategoryUtilities, NewPauseCommand())
+	shell.AddCommand("waitfor", shell.CategoryUtilities, NewWaitForCommand())
 }
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -22,6 +23,8 @@ type SetCommand struct {
 	allowEmpty       *bool
 	deleteTempOption *bool
 	configOption     *bool
+	localOption      *bool
+	globalOption     *bool
 	modifierOptions  modifiers.ModifierOptions
 	historyOptions   shell.HistoryOptions
 }
@@ -44,6 +47,8 @@ func (cmd *SetCommand) AddOptions(set shell.CmdSet) {
 	cmd.allowEmpty = set.BoolLong("empty", 0, "Allow an empty string for value")
 	cmd.deleteTempOption = set.BoolLong("clear-tmp", 0, "Remove all variables starting with $")
 	cmd.configOption = set.BoolLong("config", 0, "List configuration values starting with .")
+	cmd.localOption = set.BoolLong("local", 0, "Set the variable in the local scope of the script")
+	cmd.globalOption = set.BoolLong("global", 0, "Set the variable in the global scope")
 	_ = set.BoolLong("direct", 0, "Use direct value instead of redirecting to variable or file or history")
 	cmd.modifierOptions = modifiers.AddModifierOptions(set)
 	cmd.historyOptions = shell.AddHistoryOptions(set, shell.AllPaths)
@@ -57,6 +62,10 @@ func (cmd *SetCommand) ExtendedUsage(w io.Writer) {
 
 func (cmd *SetCommand) Execute(args []string) error {
 	argCount := len(args)
+	if *cmd.localOption && *cmd.globalOption {
+		return errors.New("--local and --global cannot be used together")
+	}
+
 	if *cmd.deleteTempOption {
 		deleteTemporary()
 		return nil
@@ -97,6 +106,8 @@ func (cmd *SetCommand) Execute(args []string) error {
 					cmd.historyOptions.SetPathOption(shell.AuthPath)
 				case "--path-cookie":
 					cmd.historyOptions.SetPathOption(shell.CookiePath)
+				case "--path-timing":
+					cmd.historyOptions.SetPathOption(shell.TimingPath)
 				case "--direct":
 				default:
 					return fmt.Errorf("Invalid option in arguments: %s", v)
@@ -144,6 +155,10 @@ func processArg(cmd *SetCommand, arg string) {
 
 			if *cmd.initOnly {
 				err = shell.InitializeGlobal(variable, value)
+			} else if *cmd.localOption {
+				err = shell.SetLocal(variable, value)
+			} else if *cmd.globalOption {
+				err = shell.SetGlobalScope(variable, value)
 			} else {
 				err = shell.SetGlobal(variable, value)
 			}
@@ -264,13 +279,18 @@ func MakeVariableFilter(filterArgs ...string) func(string, interface{}) bool {
 }
 
 func displayEntry(k string, v interface{}) {
+	scope := ""
+	if name := shell.GetVariableScope(k); name != shell.GlobalScopeName {
+		scope = " (" + name + ")"
+	}
+
 	switch t := v.(type) {
 	case string:
-		fmt.Fprintf(shell.ConsoleWriter(), "%s=%s\n", k, t)
+		fmt.Fprintf(shell.ConsoleWriter(), "%s=%s%s\n", k, t, scope)
 	case shell.Auth:
-		fmt.Fprintf(shell.ConsoleWriter(), "%s=Auth Context(%t)\n", k, t.IsAuthed())
+		fmt.Fprintf(shell.ConsoleWriter(), "%s=Auth Context(%t)%s\n", k, t.IsAuthed(), scope)
 	default:
-		fmt.Fprintf(shell.ConsoleWriter(), "%s={unsupported type}\n", k)
+		fmt.Fprintf(shell.ConsoleWriter(), "%s={unsupported type}%s\n", k, scope)
 	}
 }
 
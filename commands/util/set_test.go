@@ -178,3 +178,30 @@ func TestEnvAllowEmptyWithEmptyEnvVar(t *testing.T) {
 		t.Errorf("Env variable %s!=%s", expectedValue, value)
 	}
 }
+
+func TestSetLocalAndGlobalOptions(t *testing.T) {
+	var trueValue = true
+	varName := "SCOPETEST"
+	shell.RemoveGlobal(varName)
+	pop := shell.PushScope("test")
+
+	cmd := NewSetCommand()
+	cmd.AddOptions(shell.NewCmdSet())
+	cmd.localOption = &trueValue
+	processArg(cmd, varName+"=local")
+
+	cmd = NewSetCommand()
+	cmd.AddOptions(shell.NewCmdSet())
+	cmd.globalOption = &trueValue
+	processArg(cmd, varName+"=global")
+
+	if value := shell.GetGlobalString(varName); value != "local" {
+		t.Errorf("expected the local value but got %s", value)
+	}
+
+	pop()
+	if value := shell.GetGlobalString(varName); value != "global" {
+		t.Errorf("expected the global value but got %s", value)
+	}
+	shell.RemoveGlobal(varName)
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/brada954/restshell/shell"
+)
+
+type WaitForCommand struct {
+	// Place getopt option value pointers here
+	timeoutOption     *string
+	attemptsOption    *int
+	intervalOption    *string
+	maxIntervalOption *string
+	backoffOption     *bool
+	aborted           bool
+	wait              chan bool
+}
+
+func NewWaitForCommand() *WaitForCommand {
+	return &WaitForCommand{}
+}
+
+func (cmd *WaitForCommand) AddOptions(set shell.CmdSet) {
+	set.SetParameters("\"command\" condition")
+	set.SetUsage(func() {
+		set.PrintUsage(shell.ConsoleWriter())
+		cmd.ExtendedUsage(shell.ConsoleWriter())
+	})
+	cmd.timeoutOption = set.StringLong("timeout", 't', "30s", "Maximum time to wait (0 is no limit)", "duration")
+	cmd.attemptsOption = set.IntLong("attempts", 'a', 0, "Maximum number of attempts (0 is no limit)", "n")
+	cmd.intervalOption = set.StringLong("interval", 'i', "1s", "Time to wait between attempts", "duration")
+	cmd.maxIntervalOption = set.StringLong("max-interval", 0, "30s", "Maximum time between attempts with backoff", "duration")
+	cmd.backoffOption = set.BoolLong("backoff", 0, "Double the interval after each attempt")
+	shell.AddCommonCmdOptions(set, shell.CmdDebug, shell.CmdVerbose)
+}
+
+func (cmd *WaitForCommand) ExtendedUsage(w io.Writer) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Repeat the command until the condition is met. Conditions use the")
+	fmt.Fprintln(w, "syntax of IF blocks: OP [--not] [--var] [path options] path [value]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Example: waitfor --backoff \"GET /jobs/%%id%%\" EQ status COMPLETED")
+	fmt.Fprintf(w, "\nOperators\n")
+	lines := shell.ColumnizeTokens(shell.ConditionOperators(), 4, 15)
+	for _, v := range lines {
+		fmt.Fprintf(w, "  %s\n", v)
+	}
+}
+
+func (cmd *WaitForCommand) Execute(args []string) error {
+	// Validate arguments
+	if len(args) < 2 {
+		return shell.ErrArguments
+	}
+
+	command := args[0]
+	condition := args[1:]
+	if !shell.ContainsCommand(strings.ToUpper(condition[0]), shell.ConditionOperators()) {
+		return fmt.Errorf("invalid condition operator: %s", condition[0])
+	}
+
+	timeout, err := shell.ParseDuration(*cmd.timeoutOption, "ms")
+	if err != nil {
+		return err
+	}
+
+	interval, err := shell.ParseDuration(*cmd.intervalOption, "ms")
+	if err != nil {
+		return err
+	}
+
+	maxInterval, err := shell.ParseDuration(*cmd.maxIntervalOption, "ms")
+	if err != nil {
+		return err
+	}
+
+	// Commands executed while waiting reset the common options
+	verbose := shell.IsCmdVerboseEnabled()
+	debug := shell.IsCmdDebugEnabled()
+	attempts := *cmd.attemptsOption
+	backoff := *cmd.backoffOption
+
+	cmd.aborted = false
+	cmd.wait = make(chan bool, 1)
+	defer func() { cmd.wait = nil }()
+
+	var lastErr error
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := shell.ExecuteCommandLine(command)
+		if cmd.aborted || shell.IsInterrupt(err) {
+			return shell.NewInterruptError()
+		} else if shell.IsFlowControl(err, shell.FlowAbort) || shell.IsFlowControl(err, shell.FlowQuit) {
+			return err
+		}
+
+		if err == nil {
+			var met bool
+			met, err = shell.EvaluateCondition(condition)
+			if err == nil && met {
+				if verbose {
+					fmt.Fprintf(shell.OutputWriter(), "Condition met after %d attempt(s) in %s\n", attempt, time.Since(start).Round(time.Millisecond))
+				}
+				return nil
+			}
+		}
+		lastErr = err
+
+		if debug && err != nil {
+			fmt.Fprintf(shell.ConsoleWriter(), "Attempt %d failed: %s\n", attempt, err.Error())
+		}
+
+		elapsed := time.Since(start)
+		if (attempts > 0 && attempt >= attempts) || (timeout > 0 && elapsed+interval > timeout) {
+			message := fmt.Sprintf("condition not met after %d attempt(s) in %s", attempt, elapsed.Round(time.Millisecond))
+			if lastErr != nil {
+				message = message + ": " + lastErr.Error()
+			}
+			return errors.New(message)
+		}
+
+		if verbose {
+			fmt.Fprintf(shell.OutputWriter(), "Attempt %d: condition not met; waiting %s\n", attempt, interval)
+		}
+
+		select {
+		case <-cmd.wait:
+			return shell.NewInterruptError()
+		case <-time.After(interval):
+		}
+
+		if backoff {
+			interval = interval * 2
+			if maxInterval > 0 && interval > maxInterval {
+				interval = maxInterval
+			}
+		}
+	}
+}
+
+func (cmd *WaitForCommand) Abort() {
+	cmd.aborted = true
+	c := cmd.wait
+	if c != nil {
+		select {
+		case c <- true:
+		default:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/brada954/restshell/shell"
+)
+
+type waitForTestCommand struct {
+	count int
+}
+
+func (cmd *waitForTestCommand) Execute(args []string) error {
+	cmd.count++
+	shell.SetGlobal("waitfortest.count", strconv.Itoa(cmd.count))
+	return nil
+}
+
+func (cmd *waitForTestCommand) AddOptions(set shell.CmdSet) {
+}
+
+var waitForTester = &waitForTestCommand{}
+var addWaitForTester sync.Once
+
+func executeWaitFor(t *testing.T, attempts int, condition ...string) error {
+	t.Helper()
+	waitForTester.count = 0
+	addWaitForTester.Do(func() {
+		shell.AddCommand("waitfortest", shell.CategoryUtilities, waitForTester)
+	})
+
+	timeout := "1s"
+	interval := "1ms"
+	maxInterval := "1ms"
+	backoff := true
+
+	cmd := NewWaitForCommand()
+	cmd.AddOptions(shell.NewCmdSet())
+	cmd.timeoutOption = &timeout
+	cmd.intervalOption = &interval
+	cmd.maxIntervalOption = &maxInterval
+	cmd.backoffOption = &backoff
+	cmd.attemptsOption = &attempts
+	return cmd.Execute(append([]string{"waitfortest"}, condition...))
+}
+
+func TestWaitForConditionMet(t *testing.T) {
+	err := executeWaitFor(t, 0, "EQ", "--var", "waitfortest.count", "3")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+	if waitForTester.count != 3 {
+		t.Errorf("expected 3 attempts but executed %d", waitForTester.count)
+	}
+}
+
+func TestWaitForAttemptsExceeded(t *testing.T) {
+	err := executeWaitFor(t, 2, "EQ", "--var", "waitfortest.count", "3")
+	if err == nil {
+		t.Errorf("expected an error when attempts are exceeded")
+	}
+	if waitForTester.count != 2 {
+		t.Errorf("expected 2 attempts but executed %d", waitForTester.count)
+	}
+}
+
+func TestWaitForInvalidCondition(t *testing.T) {
+	if err := executeWaitFor(t, 1, "BOGUS", "x"); err == nil {
+		t.Errorf("expected an error for an invalid operator")
+	}
+	if waitForTester.count != 0 {
+		t.Errorf("expected the command not to execute")
+	}
+}
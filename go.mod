@@ -3,6 +3,7 @@ module github.com/brada954/restshell
 go 1.19
 
 require (
+	github.com/PaesslerAG/gval v1.0.2-0.20190803062529-6fceb06ca162
 	github.com/PaesslerAG/jsonpath v0.1.0
 	github.com/antchfx/xmlquery v1.3.1
 	github.com/pborman/getopt/v2 v2.1.0
@@ -13,7 +14,6 @@ require (
 )
 
 require (
-	github.com/PaesslerAG/gval v1.0.2-0.20190803062529-6fceb06ca162 // indirect
 	github.com/antchfx/xpath v1.1.10 // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	golang.org/x/net v0.17.0 // indirect
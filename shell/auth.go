@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -176,3 +177,53 @@ func (a QueryParamAuth) GetKeyValue(key string) (string, bool) {
 	}
 	return "", false
 }
+
+// requestAuth -- an auth context copied to a parallel branch as the headers
+// and query parameters the context adds to a request
+type requestAuth struct {
+	Header http.Header
+	Query  url.Values
+}
+
+func newRequestAuth(auth Auth) requestAuth {
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	auth.AddAuth(req)
+	return requestAuth{Header: req.Header, Query: req.URL.Query()}
+}
+
+func (a requestAuth) IsAuthed() bool {
+	return len(a.Header) > 0 || len(a.Query) > 0
+}
+
+func (a requestAuth) AddAuth(req *http.Request) {
+	for k, values := range a.Header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	if len(a.Query) == 0 {
+		return
+	}
+	newurl := req.URL.String()
+	if strings.Contains(newurl, "?") {
+		newurl = newurl + "&" + a.Query.Encode()
+	} else {
+		newurl = newurl + "?" + a.Query.Encode()
+	}
+	if result, err := url.Parse(newurl); err == nil {
+		req.URL = result
+	}
+}
+
+func (a requestAuth) ToString() string {
+	keys := make([]string, 0)
+	for k := range a.Header {
+		keys = append(keys, k)
+	}
+	for k := range a.Query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -24,6 +25,7 @@ type Benchmark struct {
 	highTimeIndex     int
 	lowTimeIndex      int
 	standardDeviation float64
+	timing            TimingSummary
 }
 
 // BenchmarkIteration -- Iteration structure implementing JobContext
@@ -33,6 +35,7 @@ type BenchmarkIteration struct {
 	Err       error
 	Messages  []string
 	Custom    interface{}
+	Timing    *RequestTiming // Phases of the request of the iteration
 	start     time.Time
 	end       time.Time
 }
@@ -57,6 +60,7 @@ func (bm *Benchmark) summarize() {
 	var lowTime int64 = math.MaxInt64
 	var highTimeIndex int = 0
 	var lowTimeIndex int = 0
+	bm.timing = TimingSummary{}
 
 	// Averge calculations
 	for k, v := range bm.Iterations {
@@ -71,6 +75,9 @@ func (bm *Benchmark) summarize() {
 				lowTime = v.WallTime
 				lowTimeIndex = k
 			}
+			if v.Timing != nil {
+				bm.timing.Add(*v.Timing)
+			}
 		}
 	}
 
@@ -145,6 +152,11 @@ func (jc *BenchmarkIteration) UpdateError(err error) {
 	jc.Err = err
 }
 
+// SetTiming -- record the timing of the request of the iteration
+func (jc *BenchmarkIteration) SetTiming(timing RequestTiming) {
+	jc.Timing = &timing
+}
+
 func (bm *Benchmark) AddIterationMessage(i int, msg string) {
 	bm.Iterations[i].Messages = append(bm.Iterations[i].Messages, msg)
 }
@@ -156,6 +168,7 @@ func (bm *Benchmark) Dump(label string, opts StandardOptions, showIterations boo
 
 	var highIndexLen = numOfDigits(bm.highTimeIndex)
 	var lowIndexLen = numOfDigits(bm.lowTimeIndex)
+	var timing = bm.TimingAverage()
 
 	if !opts.IsHeaderDisabled() {
 		var note string
@@ -164,10 +177,11 @@ func (bm *Benchmark) Dump(label string, opts StandardOptions, showIterations boo
 		}
 
 		if opts.IsCsvOutputEnabled() {
-			var headingFmt = "%[1]s,%[2]s,%[3]s,%[4]s,%[5]s,%[6]s,%[7]s,%[8]s,%[9]s,%[10]s,%[11]s,%[12]s\n"
+			var headingFmt = "%[1]s,%[2]s,%[3]s,%[4]s,%[5]s,%[6]s,%[7]s,%[8]s,%[9]s,%[10]s,%[11]s,%[12]s,%[13]s\n"
 			fmt.Fprintf(OutputWriter(),
 				headingFmt,
-				"Label", "Count", "Err", "Avg", "High", "HI", "Low", "LI", "Avg-(HL)", "Tot", "Message", note)
+				"Label", "Count", "Err", "Avg", "High", "HI", "Low", "LI", "Avg-(HL)", "Tot", "Message",
+				strings.Join(timingCsvHeadings, ","), note)
 		} else {
 			var headingFmt = "%-16[1]s  %5[2]s  %5[3]s  %8[4]s  %8[5]s%[13]*[6]s  %8[7]s%[14]*[8]s  %8[9]s  %8[10]s %8[11]s %[12]s\n"
 			fmt.Fprintf(OutputWriter(),
@@ -180,7 +194,7 @@ func (bm *Benchmark) Dump(label string, opts StandardOptions, showIterations boo
 	}
 
 	if opts.IsFormattedCsvEnabled() {
-		var displayFmt = "%s,%d,%d,%s,%s,%d,%s,%d,%s,%s,%s\n"
+		var displayFmt = "%s,%d,%d,%s,%s,%d,%s,%d,%s,%s,%s,%s\n"
 		fmt.Fprintf(OutputWriter(),
 			displayFmt,
 			label,
@@ -194,9 +208,10 @@ func (bm *Benchmark) Dump(label string, opts StandardOptions, showIterations boo
 			bm.HlAverageFmt(),
 			bm.WallTimeFmt(),
 			bm.message,
+			strings.Join(timing.csvFields(true), ","),
 		)
 	} else if opts.IsCsvOutputEnabled() {
-		var displayFmt = "%s,%d,%d,%f,%f,%d,%f,%d,%f,%f,%s\n"
+		var displayFmt = "%s,%d,%d,%f,%f,%d,%f,%d,%f,%f,%s,%s\n"
 		fmt.Fprintf(OutputWriter(),
 			displayFmt,
 			label,
@@ -210,6 +225,7 @@ func (bm *Benchmark) Dump(label string, opts StandardOptions, showIterations boo
 			bm.HlAverageInMs(),
 			bm.WallTimeInMs(),
 			bm.message,
+			strings.Join(timing.csvFields(false), ","),
 		)
 	} else {
 		var displayFmt = "%-16.16s  %5d  %5d %9s %9s(%*d) %9s(%*d) %9s %9s  %s\n"
@@ -231,6 +247,10 @@ func (bm *Benchmark) Dump(label string, opts StandardOptions, showIterations boo
 		)
 	}
 
+	if timing.Count > 0 && !opts.IsCsvOutputEnabled() {
+		fmt.Fprintf(OutputWriter(), "%-16s  %s\n", "Phases (avg)", timing.describe())
+	}
+
 	if showIterations {
 		bm.DumpIterations(opts)
 	}
@@ -287,6 +307,15 @@ func (bi *BenchmarkIteration) DumpLine(opts StandardOptions, displayFmt string,
 	}
 }
 
+// TimingAverage -- the average timing of the phases of the requests of the
+// successful iterations
+func (bm *Benchmark) TimingAverage() TimingSummary {
+	if !bm.summarized {
+		bm.summarize()
+	}
+	return bm.timing
+}
+
 func (bm *Benchmark) WallAverageFmt() string {
 	return FormatMsTime(bm.WallAverageInMs())
 }
@@ -0,0 +1,248 @@
+// Control blocks
+//
+// A control block is a group of script lines started by a block keyword
+// (e.g. IF) and terminated by a matching end keyword (e.g. ENDIF). A block
+// may be divided into clauses by keywords registered with the block
+// (e.g. ELSEIF and ELSE).
+//
+// Blocks are read completely before they are executed so lines inside a
+// block are parsed (alias expansion and variable substitution) each time
+// they are executed. Blocks may be nested.
+
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// statement -- a script line or a nested control block starting on the line
+type statement struct {
+	line  scriptLine
+	block *controlBlock
+}
+
+// blockClause -- a keyword line of a block and the statements following it
+type blockClause struct {
+	keyword string
+	line    scriptLine
+	body    []statement
+}
+
+// controlBlock -- a parsed control block with its clauses
+type controlBlock struct {
+	keyword string
+	clauses []blockClause
+}
+
+// blockHandler -- executes a parsed control block within a processor
+type blockHandler func(p *processor, block *controlBlock) error
+
+// blockDefinition -- the keywords and handler of a control block; the first
+// end keyword is the preferred keyword and the others are alternates
+type blockDefinition struct {
+	clauses []string
+	ends    []string
+	handler blockHandler
+}
+
+// end -- the preferred keyword ending the block
+func (def blockDefinition) end() string {
+	return def.ends[0]
+}
+
+// isEnd -- true if the keyword ends the block
+func (def blockDefinition) isEnd(keyword string) bool {
+	return ContainsCommand(keyword, def.ends)
+}
+
+var blockDefinitions = make(map[string]blockDefinition)
+
+// registerBlock -- Add a control block to the command processor; alternate
+// keywords may end the block as well as the end keyword
+func registerBlock(keyword string, end string, clauses []string, handler blockHandler, alternates ...string) {
+	keyword = strings.ToUpper(keyword)
+	if _, ok := blockDefinitions[keyword]; ok {
+		panic("Block added more than once: " + keyword)
+	}
+	blockDefinitions[keyword] = blockDefinition{
+		clauses: clauses,
+		ends:    append([]string{strings.ToUpper(end)}, alternates...),
+		handler: handler,
+	}
+}
+
+func addControlBlocks() {
+	registerBlock("IF", "ENDIF", []string{"ELSEIF", "ELSE"}, executeIfBlock)
+	registerBlock("FOREACH", "ENDFOREACH", []string{}, executeForeachBlock)
+	registerBlock("WHILE", "ENDWHILE", []string{}, executeWhileBlock)
+	registerBlock("UNTIL", "ENDUNTIL", []string{}, executeUntilBlock)
+	registerBlock("PROC", "ENDPROC", []string{}, executeProcBlock)
+	registerBlock("TRY", "ENDTRY", []string{"CATCH", "FINALLY"}, executeTryBlock)
+	registerBlock("PARALLEL", "END", []string{"BRANCH"}, executeParallelBlock, "ENDPARALLEL")
+}
+
+// getLineKeyword -- get the upper case first token of a raw script line ignoring
+// leading command modifiers; comments and empty lines return an empty string
+func getLineKeyword(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimLeft(text, "@$!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func isBlockKeyword(keyword string) bool {
+	_, ok := blockDefinitions[keyword]
+	return ok
+}
+
+// isBlockTerminator -- true if the keyword continues or ends a control block
+func isBlockTerminator(keyword string) bool {
+	for _, def := range blockDefinitions {
+		if def.isEnd(keyword) || ContainsCommand(keyword, def.clauses) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBlock -- read a control block starting with the header line until the
+// matching end keyword; nested blocks are read recursively
+func parseBlock(header scriptLine, next func() (scriptLine, bool)) (*controlBlock, error) {
+	keyword := getLineKeyword(header.Text)
+	def, ok := blockDefinitions[keyword]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a block command", keyword)
+	}
+
+	block := &controlBlock{keyword: keyword}
+	clause := blockClause{keyword: keyword, line: header}
+	for {
+		line, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("%s block is missing %s", keyword, def.end())
+		}
+
+		lineKeyword := getLineKeyword(line.Text)
+		if def.isEnd(lineKeyword) {
+			block.clauses = append(block.clauses, clause)
+			return block, nil
+		} else if ContainsCommand(lineKeyword, def.clauses) {
+			block.clauses = append(block.clauses, clause)
+			clause = blockClause{keyword: lineKeyword, line: line}
+		} else if isBlockKeyword(lineKeyword) {
+			nested, err := parseBlock(line, next)
+			if err != nil {
+				return nil, err
+			}
+			clause.body = append(clause.body, statement{line: line, block: nested})
+		} else if isBlockTerminator(lineKeyword) {
+			return nil, fmt.Errorf("%s found inside %s block", lineKeyword, keyword)
+		} else {
+			clause.body = append(clause.body, statement{line: line})
+		}
+	}
+}
+
+// executeBlock -- execute a control block reporting errors like a command
+func (p *processor) executeBlock(block *controlBlock) {
+	def := blockDefinitions[block.keyword]
+	if err := def.handler(p, block); err != nil {
+		p.setLine(block.clauses[0].line)
+		p.reportError(block.keyword, err)
+	}
+}
+
+// executeStatements -- execute the statements of a block until complete or
+// the processor is requested to quit or a command is aborted
+func (p *processor) executeStatements(statements []statement) {
+	for _, s := range statements {
+		if p.isStopped() {
+			return
+		}
+		if s.block != nil {
+			p.executeBlock(s.block)
+		} else {
+			p.executeLine(s.line)
+		}
+	}
+}
+
+// isStopped -- true when executing blocks must stop
+func (p *processor) isStopped() bool {
+	return p.quit || p.interrupted || p.returned || p.trapped != nil
+}
+
+// prepareClause -- parse the keyword line of a clause performing variable
+// substitution and return the arguments following the keyword
+func (p *processor) prepareClause(clause blockClause) ([]string, error) {
+	p.setLine(clause.line)
+	line, err := NewCommandLine(clause.line.Text, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if line.Echo {
+		fmt.Println(line.CmdLine)
+	}
+
+	if scriptDebugger.shouldStop(clause.line, line.Command) {
+		if err := scriptDebugger.prompt(); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens := line.GetTokens()
+	return tokens[1:], nil
+}
+
+// isExpressionClause -- true when the condition of a clause contains an
+// expression so a single argument is the value of the expression
+func isExpressionClause(clause blockClause) bool {
+	return strings.Contains(clause.line.Text, "%%=")
+}
+
+// executeIfBlock -- execute the first clause of an IF block with a condition
+// that is met or the ELSE clause
+func executeIfBlock(p *processor, block *controlBlock) error {
+	if err := validateIfBlock(block); err != nil {
+		return err
+	}
+
+	for _, clause := range block.clauses {
+		if clause.keyword != "ELSE" {
+			args, err := p.prepareClause(clause)
+			if err != nil {
+				return err
+			}
+
+			met, err := evaluateCondition(args, isExpressionClause(clause))
+			if err != nil {
+				return err
+			}
+			if !met {
+				continue
+			}
+		}
+		p.executeStatements(clause.body)
+		return nil
+	}
+	return nil
+}
+
+// validateIfBlock -- validate the order of the clauses of an IF block
+func validateIfBlock(block *controlBlock) error {
+	for i, clause := range block.clauses {
+		if clause.keyword == "ELSE" && i != len(block.clauses)-1 {
+			return errors.New("ELSE must be the last clause of an IF block")
+		}
+	}
+	return nil
+}
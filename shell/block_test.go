@@ -0,0 +1,142 @@
+package shell
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// recordCommand -- test command recording the arguments of each execution
+type recordCommand struct {
+	lines []string
+}
+
+func (r *recordCommand) Execute(args []string) error {
+	if len(args) > 0 && args[0] == "fail" {
+		return errors.New("record failed")
+	} else if len(args) > 0 && args[0] == "abort" {
+		return NewFlowError("record aborted", FlowAbort)
+	} else if len(args) > 0 && args[0] == "panic" {
+		panic("record panicked")
+	}
+	r.lines = append(r.lines, strings.Join(args, " "))
+	SetGlobal("record.count", strconv.Itoa(len(r.lines)))
+	return nil
+}
+
+func (r *recordCommand) AddOptions(set CmdSet) {
+}
+
+var testRecorder = &recordCommand{}
+
+func runTestScript(script string) []string {
+	if _, ok := cmdMap["RECORD"]; !ok {
+		AddCommand("record", CategoryUtilities, testRecorder)
+	}
+	testRecorder.lines = nil
+	SetGlobal("record.count", "0")
+	LastError = 0
+	CommandProcessor("", strings.NewReader(script), false, false)
+	return testRecorder.lines
+}
+
+func verifyRecorded(t *testing.T, got []string, expected ...string) {
+	t.Helper()
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestIfBlockExecutesFirstMatchingClause(t *testing.T) {
+	SetGlobal("blocktest", "2")
+	script := `
+record start
+IF EQ --var blocktest 1
+  record one
+ELSEIF EQ --var blocktest 2
+  record two
+ELSE
+  record other
+ENDIF
+record end
+`
+	verifyRecorded(t, runTestScript(script), "start", "two", "end")
+}
+
+func TestIfBlockExecutesElseClause(t *testing.T) {
+	SetGlobal("blocktest", "x")
+	script := `
+if eq --var blocktest y
+  record yes
+else
+  record no
+endif
+`
+	verifyRecorded(t, runTestScript(script), "no")
+}
+
+func TestIfBlockNested(t *testing.T) {
+	SetGlobal("blocktest", "5")
+	script := `
+IF GT --var blocktest 1
+  IF LT --var blocktest 3
+    record small
+  ELSE
+    record large
+  ENDIF
+  record after
+ENDIF
+`
+	verifyRecorded(t, runTestScript(script), "large", "after")
+}
+
+func TestIfBlockNotOption(t *testing.T) {
+	SetGlobal("blocktest", "5")
+	script := `
+IF EQ --not --var blocktest 5
+  record wrong
+ENDIF
+IF NEX --var blocktest.missing
+  record missing
+ENDIF
+`
+	verifyRecorded(t, runTestScript(script), "missing")
+}
+
+func TestIfBlockMissingEndIsNotExecuted(t *testing.T) {
+	script := `
+IF LASTERR --not
+  record inside
+`
+	verifyRecorded(t, runTestScript(script))
+	if LastError == 0 {
+		t.Errorf("expected an error for the missing ENDIF")
+	}
+}
+
+func TestUnmatchedEndIsAnError(t *testing.T) {
+	script := `
+record before
+ENDIF
+`
+	verifyRecorded(t, runTestScript(script), "before")
+	if LastError == 0 {
+		t.Errorf("expected an error for the unmatched ENDIF")
+	}
+}
+
+func TestElseMustBeLastClause(t *testing.T) {
+	script := `
+IF LASTERR
+ELSE
+  record else
+ELSEIF LASTERR
+  record elseif
+ENDIF
+`
+	verifyRecorded(t, runTestScript(script))
+	if LastError == 0 {
+		t.Errorf("expected an error for ELSE before ELSEIF")
+	}
+}
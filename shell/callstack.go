@@ -0,0 +1,67 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// callFrame -- a script or procedure executing in the command processor and
+// the line it is executing
+type callFrame struct {
+	name string
+	line scriptLine
+}
+
+var callStack = make([]*callFrame, 0)
+
+// traceEnabled -- print the call stack when a script is aborted (run --trace)
+var traceEnabled = false
+var tracedAbort error
+
+// pushCallFrame -- add a frame to the call stack and return a function to remove it
+func pushCallFrame(name string) (*callFrame, func()) {
+	frame := &callFrame{name: name}
+	callStack = append(callStack, frame)
+	depth := len(callStack)
+	return frame, func() {
+		if len(callStack) >= depth {
+			callStack = callStack[:depth-1]
+		}
+		if len(callStack) == 0 {
+			endDebugSession()
+		}
+	}
+}
+
+// currentLocation -- the file:line of the line executing in the innermost
+// script or procedure; empty when not executing a script
+func currentLocation() string {
+	if len(callStack) == 0 {
+		return ""
+	}
+	return callStack[len(callStack)-1].line.Location()
+}
+
+// WriteCallStack -- write the call stack of executing scripts and procedures
+// starting with the innermost frame
+func WriteCallStack(w io.Writer) {
+	fmt.Fprintln(w, "Call stack:")
+	for i := len(callStack) - 1; i >= 0; i-- {
+		frame := callStack[i]
+		location := frame.line.Location()
+		if len(location) == 0 {
+			location = "line " + fmt.Sprint(frame.line.Line)
+		}
+		fmt.Fprintf(w, "  at %s (%s) %s\n", frame.name, location, strings.TrimSpace(frame.line.Text))
+	}
+}
+
+// traceAbort -- write the call stack once for an abort when tracing is enabled
+func traceAbort(err error) {
+	if !traceEnabled || err == tracedAbort {
+		return
+	}
+	tracedAbort = err
+	WriteCallStack(ErrorWriter())
+}
@@ -0,0 +1,103 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptReaderTracksLines(t *testing.T) {
+	reader := newScriptReader(strings.NewReader("one\ntwo\n"), "test.rshell")
+	first, _ := reader.ReadLine()
+	second, _ := reader.ReadLine()
+	if _, ok := reader.ReadLine(); ok {
+		t.Errorf("expected end of input")
+	}
+	if first.Location() != "test.rshell:1" || second.Location() != "test.rshell:2" {
+		t.Errorf("unexpected locations: %s %s", first.Location(), second.Location())
+	}
+
+	interactive, _ := newScriptReader(strings.NewReader("one\n"), "").ReadLine()
+	if interactive.Location() != "" {
+		t.Errorf("expected no location for interactive input: %s", interactive.Location())
+	}
+}
+
+func TestErrorLocationInScriptsAndProcedures(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "errors.rshell")
+	os.WriteFile(file, []byte("PROC failing\n  record fail\nENDPROC\nrecord one\nrecord fail\n"), 0644)
+	defer RemoveProcedure("FAILING")
+
+	script := `
+TRY
+  run -s ` + file + `
+CATCH
+  record caught %%$error.location%%
+ENDTRY
+TRY
+  failing
+CATCH
+  record caught %%$error.location%%
+ENDTRY
+`
+	verifyRecorded(t, runTestScript(script), "one", "caught "+file+":5", "caught "+file+":2")
+}
+
+func TestWriteCallStack(t *testing.T) {
+	frame, pop := pushCallFrame("run outer.rshell")
+	frame.line = scriptLine{Text: "  inner", File: "outer.rshell", Line: 3}
+	inner, popInner := pushCallFrame("proc INNER")
+	inner.line = scriptLine{Text: "record abort", File: "lib.rshell", Line: 7}
+
+	if currentLocation() != "lib.rshell:7" {
+		t.Errorf("unexpected current location: %s", currentLocation())
+	}
+
+	var buf bytes.Buffer
+	WriteCallStack(&buf)
+	expected := "Call stack:\n  at proc INNER (lib.rshell:7) record abort\n  at run outer.rshell (outer.rshell:3) inner\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected call stack:\n%s", buf.String())
+	}
+
+	popInner()
+	pop()
+	if len(callStack) != 0 || currentLocation() != "" {
+		t.Errorf("expected an empty call stack")
+	}
+}
+
+func TestRunTraceWritesCallStackOnAbort(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "trace.rshell")
+	os.WriteFile(file, []byte("PROC aborting\n  record abort\nENDPROC\nrecord one\naborting\nrecord never\n"), 0644)
+	defer RemoveProcedure("ABORTING")
+
+	var buf bytes.Buffer
+	saved := currentError
+	currentError = &buf
+	defer func() { currentError = saved }()
+
+	verifyRecorded(t, runTestScript("run -s --trace "+file+"\n"), "one")
+	output := buf.String()
+	expected := []string{
+		file + ":2: RECORD: record aborted",
+		"Call stack:",
+		"  at proc ABORTING (" + file + ":2) record abort",
+		"  at run " + file + " (" + file + ":5) aborting",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected %q in output:\n%s", line, output)
+		}
+	}
+	if strings.Count(output, "Call stack:") != 1 {
+		t.Errorf("expected the call stack once:\n%s", output)
+	}
+	if traceEnabled {
+		t.Errorf("expected trace to be disabled after run")
+	}
+}
@@ -0,0 +1,159 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// completeLine -- complete the word at the end of the text (the line up to
+// the cursor) with commands and aliases, sub-commands, the long options of
+// the command, variable names inside %% or file names for RUN and LOAD
+func completeLine(text string) completion {
+	start := strings.LastIndexAny(text, " \t") + 1
+	word := text[start:]
+	prior := strings.Fields(text[:start])
+	c := completion{start: utf8.RuneCountInString(text[:start]), suffix: " "}
+
+	if i := strings.LastIndex(word, "%%"); i >= 0 && strings.Count(word, "%%")%2 == 1 {
+		c.candidates = completeVariables(word[:i+2], word[i+2:])
+		c.suffix = "%%"
+		return c
+	}
+
+	if len(prior) == 0 {
+		name := strings.TrimLeft(word, "@$!")
+		c.candidates = completeCommands(word[:len(word)-len(name)], name)
+		return c
+	}
+
+	command := getCompletionCommand(prior[0])
+	cmd, ok := cmdMap[command]
+	if !ok {
+		return c
+	}
+
+	if strings.HasPrefix(word, "-") {
+		c.candidates = sortedCandidates(getCommandOptions(cmd), word, false)
+	} else if subCommands, ok := cmdSubCommands[command]; ok && len(prior) == 1 {
+		c.candidates = sortedCandidates(matchCase(subCommands, word), word, true)
+	} else if command == "RUN" || command == "LOAD" {
+		c.candidates = completeFiles(word)
+	}
+	return c
+}
+
+// getCompletionCommand -- the command of the first word of a line without
+// modifiers and with aliases expanded
+func getCompletionCommand(word string) string {
+	word = strings.TrimLeft(word, "@$!")
+	if alias, err := GetAlias(word); err == nil {
+		if fields := strings.Fields(alias); len(fields) > 0 {
+			word = strings.TrimLeft(fields[0], "@$!")
+		}
+	}
+	return strings.ToUpper(word)
+}
+
+func completeCommands(modifiers string, prefix string) []string {
+	names := make([]string, 0, len(cmdMap))
+	for name := range cmdMap {
+		names = append(names, name)
+	}
+	names = append(names, GetAllAliasKeys()...)
+
+	candidates := sortedCandidates(matchCase(names, prefix), prefix, true)
+	for i := range candidates {
+		candidates[i] = modifiers + candidates[i]
+	}
+	return candidates
+}
+
+func completeVariables(leading string, prefix string) []string {
+	names := make([]string, 0)
+	EnumerateGlobals(func(key string, value interface{}) {
+		names = append(names, key)
+	}, nil)
+
+	candidates := sortedCandidates(names, prefix, false)
+	for i := range candidates {
+		candidates[i] = leading + candidates[i]
+	}
+	return candidates
+}
+
+// completeFiles -- the files and directories (ending with a separator)
+// starting with the path; hidden files are included when requested
+func completeFiles(path string) []string {
+	dir, prefix := filepath.Split(path)
+	search := dir
+	if len(search) == 0 {
+		search = "."
+	}
+
+	entries, err := os.ReadDir(search)
+	if err != nil {
+		return []string{}
+	}
+
+	candidates := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += string(filepath.Separator)
+		}
+		candidates = append(candidates, dir+name)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// getCommandOptions -- the long options of a command including the common options
+func getCommandOptions(cmd Command) []string {
+	set := NewCmdSet()
+	InitializeCommonCmdOptions(set, CmdHelp)
+	cmd.AddOptions(set)
+
+	options := make([]string, 0)
+	for _, name := range CmdLongOptions(set) {
+		options = append(options, "--"+name)
+	}
+	return options
+}
+
+// matchCase -- upper case names when the word is upper case otherwise lower case
+func matchCase(names []string, word string) []string {
+	upper := len(word) > 0 && strings.ToUpper(word) == word && strings.ToLower(word) != word
+	result := make([]string, len(names))
+	for i, name := range names {
+		if upper {
+			result[i] = strings.ToUpper(name)
+		} else {
+			result[i] = strings.ToLower(name)
+		}
+	}
+	return result
+}
+
+// sortedCandidates -- the sorted unique values starting with the prefix
+func sortedCandidates(values []string, prefix string, ignoreCase bool) []string {
+	found := make(map[string]bool)
+	result := make([]string, 0)
+	for _, value := range values {
+		match := strings.HasPrefix(value, prefix)
+		if ignoreCase {
+			match = strings.HasPrefix(strings.ToLower(value), strings.ToLower(prefix))
+		}
+		if match && !found[value] {
+			found[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
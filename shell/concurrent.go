@@ -42,6 +42,12 @@ type JobContext interface {
 	UpdateError(error)
 }
 
+// TimingContext is implemented by job contexts recording the timing of the
+// request of an iteration
+type TimingContext interface {
+	SetTiming(RequestTiming)
+}
+
 // JobFunction -- Function prototype for a function that will perform an instance of the job
 type JobFunction func() (*RestResponse, error)
 
@@ -86,6 +92,7 @@ func NewJobProcessor(logger Logger, maker JobMaker, monitor JobMonitor, completi
 }
 
 func (jp JobProcessor) RunProcessor(iterations int, concurrency int, duration time.Duration, cancelPtr *bool) {
+	defer startConcurrentJobs()() // Ends after the workers exit
 	var waitGroup sync.WaitGroup
 	var endTime time.Time
 	logger := jp.logger
@@ -0,0 +1,214 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operators supported in conditions; they follow the ASSERT sub-commands
+var conditionOperators = []string{"EQ", "NEQ", "GT", "GTE", "LT", "LTE", "REGMATCH",
+	"EX", "NEX", "NIL", "NNIL", "HSTATUS", "ISERR", "NOERR", "LASTERR"}
+
+// ConditionOperators -- return the list of operators supported by conditions
+func ConditionOperators() []string {
+	return SortedStringSlice(conditionOperators)
+}
+
+// EvaluateCondition -- evaluate a condition written like an assertion:
+//
+//	OP [options] [path|variable] [value]
+//
+// Paths reference the last history result using the same path options as
+// ASSERT (--path-header, --path-cookie, --path-auth, --path-timing), or with --var the path
+// is a variable name. LASTERR tests the error state of the last command and
+// --not negates the result of the condition. A condition may also be a
+// single value that is empty, true, false or a number; the value is false
+// when empty, 0 or false like the conditions of run --cond.
+func EvaluateCondition(args []string) (bool, error) {
+	return evaluateCondition(args, false)
+}
+
+// evaluateCondition -- evaluate a condition where a single argument that is
+// the result of an expression (e.g. IF %%= count %%) is tested as a value
+func evaluateCondition(args []string, expression bool) (bool, error) {
+	c, err := parseCondition(args, expression)
+	if err != nil {
+		return false, err
+	}
+
+	met, err := evaluateOperator(c.op, c.params, c.isVar, c.historyOptions)
+	if err != nil {
+		return false, err
+	}
+	return met != c.not, nil
+}
+
+// condition -- the parsed operator, options and parameters of a condition
+type condition struct {
+	op             string
+	params         []string
+	not            bool
+	isVar          bool
+	historyOptions HistoryOptions
+}
+
+func parseCondition(args []string, expression bool) (*condition, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing condition")
+	}
+
+	op := strings.ToUpper(args[0])
+	if len(args) == 1 && (expression || (!ContainsCommand(op, conditionOperators) && isConditionValue(args[0]))) {
+		// A single value is tested like the expression of run --cond
+		return &condition{op: "VALUE", params: args}, nil
+	}
+	if !ContainsCommand(op, conditionOperators) {
+		return nil, fmt.Errorf("invalid condition operator: %s", args[0])
+	}
+
+	set := NewCmdSet()
+	notOption := set.BoolLong("not", 0, "Negate the condition")
+	varOption := set.BoolLong("var", 0, "Use the path as a variable name")
+	historyOptions := AddHistoryOptions(set, AlternatePaths)
+	set.Reset()
+	if err := CmdParse(set, makeSubTokenArray(op, args[1:])); err != nil {
+		return nil, err
+	}
+
+	return &condition{
+		op:             op,
+		params:         set.Args(),
+		not:            *notOption,
+		isVar:          *varOption,
+		historyOptions: historyOptions,
+	}, nil
+}
+
+// isConditionValue -- true when a single argument is a value to test rather
+// than a misspelled operator or an unknown variable
+func isConditionValue(value string) bool {
+	if len(value) == 0 || strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+func evaluateOperator(op string, params []string, isVar bool, historyOptions HistoryOptions) (bool, error) {
+	switch op {
+	case "VALUE":
+		return IsExpressionTrue(params[0]), nil
+	case "LASTERR":
+		return LastError != 0, nil
+	case "ISERR", "NOERR", "HSTATUS":
+		result, err := PeekResult(0)
+		if err != nil {
+			return false, err
+		}
+		return evaluateResultOperator(op, params, result)
+	}
+
+	if len(params) < 1 {
+		return false, ErrArguments
+	}
+
+	node, err := getConditionNode(params[0], isVar, historyOptions)
+	switch op {
+	case "EX":
+		return err == nil, nil
+	case "NEX":
+		return err != nil, nil
+	}
+	if err != nil && err != ErrNotFound {
+		return false, err
+	}
+
+	switch op {
+	case "NIL":
+		return node == nil, nil
+	case "NNIL":
+		return node != nil, nil
+	}
+
+	if len(params) < 2 {
+		return false, ErrArguments
+	}
+	value := params[1]
+
+	if op == "REGMATCH" {
+		str, err := ConvertNodeValueToString(node)
+		if err != nil {
+			return false, err
+		}
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", value, err.Error())
+		}
+		return regex.MatchString(str), nil
+	}
+
+	comp, err := CompareNodeValue(node, value)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case "EQ":
+		return comp == 0, nil
+	case "NEQ":
+		return comp != 0, nil
+	case "GT":
+		return comp > 0, nil
+	case "GTE":
+		return comp >= 0, nil
+	case "LT":
+		return comp < 0, nil
+	case "LTE":
+		return comp <= 0, nil
+	}
+	return false, ErrArguments
+}
+
+func evaluateResultOperator(op string, params []string, result Result) (bool, error) {
+	switch op {
+	case "ISERR":
+		return result.Error != nil, nil
+	case "NOERR":
+		return result.Error == nil, nil
+	case "HSTATUS":
+		if len(params) != 1 {
+			return false, ErrArguments
+		}
+		if strings.ToUpper(params[0]) == "OK" || strings.ToUpper(params[0]) == "SUCCESS" {
+			return result.HttpStatus == 200 || result.HttpStatus == 201, nil
+		}
+		status, err := strconv.Atoi(params[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid status argument: %s", params[0])
+		}
+		return result.HttpStatus == status, nil
+	}
+	return false, ErrArguments
+}
+
+// getConditionNode -- get the value of a variable or a history path; variables
+// containing numbers are returned as numbers so they compare numerically
+func getConditionNode(path string, isVar bool, historyOptions HistoryOptions) (interface{}, error) {
+	if !isVar {
+		return historyOptions.GetNodeFromHistory(0, path)
+	}
+
+	value := GetGlobal(path)
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	if str, ok := value.(string); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+			return f, nil
+		}
+	}
+	return value, nil
+}
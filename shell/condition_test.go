@@ -0,0 +1,69 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestEvaluateConditionWithVariables(t *testing.T) {
+	SetGlobal("condtest.num", "10")
+	SetGlobal("condtest.str", "abc")
+
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"EQ", "--var", "condtest.num", "10"}, true},
+		{[]string{"eq", "--var", "condtest.num", "10.0"}, true},
+		{[]string{"GT", "--var", "condtest.num", "9"}, true},
+		{[]string{"GT", "--var", "condtest.num", "100"}, false},
+		{[]string{"LTE", "--var", "condtest.num", "10"}, true},
+		{[]string{"NEQ", "--var", "condtest.str", "abc"}, false},
+		{[]string{"NEQ", "--not", "--var", "condtest.str", "abc"}, true},
+		{[]string{"REGMATCH", "--var", "condtest.str", "^a.c$"}, true},
+		{[]string{"EX", "--var", "condtest.str"}, true},
+		{[]string{"NEX", "--var", "condtest.none"}, true},
+		{[]string{"NIL", "--var", "condtest.none"}, true},
+		{[]string{"NNIL", "--var", "condtest.num"}, true},
+	}
+
+	for _, test := range tests {
+		met, err := EvaluateCondition(test.args)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %s", test.args, err.Error())
+		} else if met != test.expected {
+			t.Errorf("%v: expected %v but got %v", test.args, test.expected, met)
+		}
+	}
+}
+
+func TestEvaluateConditionErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"BOGUS", "--var", "x", "1"},
+		{"EQ", "--var", "condtest.num"},
+		{"EQ"},
+		{"LASTER"},
+		{"%%condtest.missing%%"},
+	}
+
+	for _, args := range tests {
+		if _, err := EvaluateCondition(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestEvaluateConditionLastError(t *testing.T) {
+	saved := LastError
+	defer func() { LastError = saved }()
+
+	LastError = 1
+	if met, _ := EvaluateCondition([]string{"LASTERR"}); !met {
+		t.Errorf("expected LASTERR to be met when LastError is set")
+	}
+
+	LastError = 0
+	if met, _ := EvaluateCondition([]string{"LASTERR"}); met {
+		t.Errorf("expected LASTERR not to be met when LastError is clear")
+	}
+}
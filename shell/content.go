@@ -0,0 +1,97 @@
+// Binary content
+//
+// Results of binary content types keep the raw bytes of the response and
+// record the size, SHA-256 and detected MIME type of the content. The body
+// map of these results contains the paths size, sha256 and mimetype so
+// assertions can check them:
+//
+//	assert eq mimetype image/png
+//
+// DOWNLOAD streams a response to a file and records the same information
+// with the path file.
+
+package shell
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+)
+
+// sniffLength -- the number of bytes used to detect the MIME type
+const sniffLength = 512
+
+// ContentInfo -- the size, hash and detected type of binary content
+type ContentInfo struct {
+	File     string // File the content was saved to (downloads)
+	Size     int64
+	Sha256   string
+	MimeType string // MIME type detected from the content
+}
+
+// NewContentInfo -- get the content information of data
+func NewContentInfo(data []byte) ContentInfo {
+	hasher := NewContentHasher()
+	hasher.Write(data)
+	return hasher.Info()
+}
+
+// historyMap -- the content information as a body map
+func (info ContentInfo) historyMap() HistoryMap {
+	data := map[string]interface{}{
+		"size":     float64(info.Size),
+		"sha256":   info.Sha256,
+		"mimetype": info.MimeType,
+	}
+	if len(info.File) > 0 {
+		data["file"] = info.File
+	}
+	return &JsonMap{data: data}
+}
+
+// ContentHasher -- a writer computing the content information of the data
+// written to it
+type ContentHasher struct {
+	hash  hash.Hash
+	size  int64
+	sniff []byte
+}
+
+func NewContentHasher() *ContentHasher {
+	return &ContentHasher{hash: sha256.New(), sniff: make([]byte, 0, sniffLength)}
+}
+
+func (h *ContentHasher) Write(p []byte) (int, error) {
+	if remaining := sniffLength - len(h.sniff); remaining > 0 {
+		if remaining > len(p) {
+			remaining = len(p)
+		}
+		h.sniff = append(h.sniff, p[:remaining]...)
+	}
+	h.size += int64(len(p))
+	return h.hash.Write(p)
+}
+
+// Size -- the number of bytes written
+func (h *ContentHasher) Size() int64 {
+	return h.size
+}
+
+// Info -- the content information of the data written
+func (h *ContentHasher) Info() ContentInfo {
+	return ContentInfo{
+		Size:     h.size,
+		Sha256:   hex.EncodeToString(h.hash.Sum(nil)),
+		MimeType: http.DetectContentType(h.sniff),
+	}
+}
+
+// describe -- a line describing the content
+func (info ContentInfo) describe() string {
+	if len(info.File) > 0 {
+		return fmt.Sprintf("Saved %d bytes (%s) to %s; sha256 %s", info.Size, info.MimeType, info.File, info.Sha256)
+	}
+	return fmt.Sprintf("Binary content of %d bytes (%s); sha256 %s", info.Size, info.MimeType, info.Sha256)
+}
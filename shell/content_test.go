@@ -0,0 +1,105 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var pngContent = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\xff\xfe")
+
+func verifyContentNode(t *testing.T, result Result, path string, expected string) {
+	t.Helper()
+	node, err := result.BodyMap.GetNode(path)
+	if err != nil {
+		t.Errorf("unable to get %s: %s", path, err.Error())
+		return
+	}
+	if c, err := CompareNodeValue(node, expected); err != nil || c != 0 {
+		t.Errorf("expected %s to be %s but got %v", path, expected, node)
+	}
+}
+
+func TestBinaryResultKeepsRawContent(t *testing.T) {
+	if err := PushResponse(makeRestResponse(string(pngContent), "image/png", 200), nil); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	result, _ := PeekResult(0)
+	if !bytes.Equal(result.Raw, pngContent) {
+		t.Errorf("expected the raw bytes of the response")
+	}
+
+	sum := sha256.Sum256(pngContent)
+	verifyContentNode(t, result, "size", "19")
+	verifyContentNode(t, result, "sha256", hex.EncodeToString(sum[:]))
+	verifyContentNode(t, result, "mimetype", "image/png")
+}
+
+func TestTextResultHasNoContentInfo(t *testing.T) {
+	PushResponse(makeRestResponse(`{"a":1}`, "application/json", 200), nil)
+	if result, _ := PeekResult(0); result.Raw != nil || result.Content != nil {
+		t.Errorf("expected no binary content for a json result")
+	}
+}
+
+func TestBinaryContentTypeIgnoresParameters(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"application/octet-stream":                 true,
+		"application/octet-stream; charset=binary": true,
+		"Application/PDF ; name=report.pdf":        true,
+		"image/png;q=1":                            true,
+		"image/svg+xml; charset=utf-8":             false,
+		"application/json; charset=utf-8":          false,
+	} {
+		if isBinaryContentType(contentType) != expected {
+			t.Errorf("expected binary %v for %q", expected, contentType)
+		}
+	}
+}
+
+func TestDoMethodToWriterStreamsSuccessfulResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngContent)
+	}))
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	hasher := NewContentHasher()
+	open := func(resp *http.Response) (io.Writer, error) {
+		return io.MultiWriter(&buffer, hasher), nil
+	}
+
+	client := NewRestClient()
+	resp, written, err := client.DoMethodToWriter(http.MethodGet, nil, server.URL+"/image.png", open)
+	if err != nil {
+		t.Fatalf("download failed: %s", err.Error())
+	}
+	if written != int64(len(pngContent)) || !bytes.Equal(buffer.Bytes(), pngContent) || resp.Text != "" {
+		t.Errorf("expected the body to be streamed to the writer")
+	}
+
+	info := hasher.Info()
+	info.File = "image.png"
+	PushDownload(resp, info, nil)
+	result, _ := PeekResult(0)
+	verifyContentNode(t, result, "file", "image.png")
+	verifyContentNode(t, result, "size", "19")
+	verifyContentNode(t, result, "mimetype", "image/png")
+
+	resp, _, err = client.DoMethodToWriter(http.MethodGet, nil, server.URL+"/missing", func(*http.Response) (io.Writer, error) {
+		return nil, errors.New("unexpected open")
+	})
+	if err != nil || resp.GetStatus() != http.StatusNotFound || resp.Text != "not found\n" {
+		t.Errorf("expected the error response to be read: %v", err)
+	}
+}
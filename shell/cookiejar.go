@@ -0,0 +1,392 @@
+// Cookie jar
+//
+// HTTP commands share a cookie jar for the session: the cookies set by a
+// response are sent with the following requests matching their domain, path
+// and secure flag until they expire. The --no-jar option sends a request
+// without the jar and the cookies command lists, clears, exports and imports
+// the jar as a Netscape cookies.txt file.
+
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetscapeCookieHeader -- the first line of a Netscape cookies.txt file
+const NetscapeCookieHeader = "# Netscape HTTP Cookie File"
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+// JarCookie -- a cookie stored in the jar
+type JarCookie struct {
+	Name     string
+	Value    string
+	Domain   string    // Domain without a leading dot
+	Path     string    // Path of the requests receiving the cookie
+	HostOnly bool      // Only sent to the domain; otherwise to its subdomains as well
+	Secure   bool      // Only sent with https requests
+	HttpOnly bool      // Recorded for exports
+	Expires  time.Time // Zero for session cookies
+	created  int64     // Order of the cookies with the same path
+}
+
+// CookieJar -- a cookie jar for the session implementing http.CookieJar
+type CookieJar struct {
+	mutex   sync.Mutex
+	cookies map[string]*JarCookie
+	created int64
+}
+
+var sessionJar = NewCookieJar()
+
+// NewCookieJar -- create an empty cookie jar
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[string]*JarCookie)}
+}
+
+// GetCookieJar -- the cookie jar of the session
+func GetCookieJar() *CookieJar {
+	return sessionJar
+}
+
+// IsExpired -- true when the cookie expired at a time
+func (c *JarCookie) IsExpired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// IsSession -- true for cookies without an expiry
+func (c *JarCookie) IsSession() bool {
+	return c.Expires.IsZero()
+}
+
+func (c *JarCookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+// matches -- true when the cookie is sent with a request of a host and path
+func (c *JarCookie) matches(host string, path string, secure bool) bool {
+	if c.Secure && !secure {
+		return false
+	}
+	if c.HostOnly {
+		if host != c.Domain {
+			return false
+		}
+	} else if !domainMatch(host, c.Domain) {
+		return false
+	}
+	return pathMatch(path, c.Path)
+}
+
+// SetCookies -- store the cookies of a response to a URL; cookies with an
+// expiry in the past remove the cookie from the jar
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host := canonicalHost(u.Hostname())
+	now := time.Now()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, cookie := range cookies {
+		c, ok := newJarCookie(host, u.Path, cookie, now)
+		if !ok {
+			continue
+		}
+		key := c.key()
+		if c.IsExpired(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		if existing, ok := j.cookies[key]; ok {
+			c.created = existing.created
+		} else {
+			j.created++
+			c.created = j.created
+		}
+		j.cookies[key] = c
+	}
+}
+
+// newJarCookie -- the jar cookie of a response cookie; false when the domain
+// of the cookie does not match the host
+func newJarCookie(host string, requestPath string, cookie *http.Cookie, now time.Time) (*JarCookie, bool) {
+	c := &JarCookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   host,
+		HostOnly: true,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		Path:     cookie.Path,
+	}
+
+	if domain := canonicalHost(strings.TrimPrefix(cookie.Domain, ".")); len(domain) > 0 && domain != host {
+		// Only a parent domain with a dot of a host name is accepted
+		if net.ParseIP(host) != nil || !strings.Contains(domain, ".") || !domainMatch(host, domain) {
+			return nil, false
+		}
+		c.Domain = domain
+		c.HostOnly = false
+	} else if len(domain) > 0 && net.ParseIP(host) == nil {
+		c.HostOnly = false
+	}
+
+	if len(c.Path) == 0 || c.Path[0] != '/' {
+		c.Path = defaultCookiePath(requestPath)
+	}
+
+	if cookie.MaxAge < 0 {
+		c.Expires = now
+	} else if cookie.MaxAge > 0 {
+		c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	} else if !cookie.Expires.IsZero() {
+		c.Expires = cookie.Expires
+	}
+	return c, true
+}
+
+// Cookies -- the cookies sent with a request to a URL; longer paths first
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host := canonicalHost(u.Hostname())
+	path := u.Path
+	if len(path) == 0 {
+		path = "/"
+	}
+	now := time.Now()
+
+	j.mutex.Lock()
+	matches := make([]*JarCookie, 0)
+	for key, c := range j.cookies {
+		if c.IsExpired(now) {
+			delete(j.cookies, key)
+		} else if c.matches(host, path, u.Scheme == "https") {
+			matches = append(matches, c)
+		}
+	}
+	j.mutex.Unlock()
+
+	sort.Slice(matches, func(i, k int) bool {
+		if len(matches[i].Path) != len(matches[k].Path) {
+			return len(matches[i].Path) > len(matches[k].Path)
+		}
+		return matches[i].created < matches[k].created
+	})
+
+	cookies := make([]*http.Cookie, 0, len(matches))
+	for _, c := range matches {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+// List -- the cookies of the jar sorted by domain, path and name; a domain
+// limits the list to the cookies of the domain and its subdomains
+func (j *CookieJar) List(domain string) []JarCookie {
+	domain = canonicalHost(strings.TrimPrefix(domain, "."))
+	now := time.Now()
+
+	j.mutex.Lock()
+	list := make([]JarCookie, 0, len(j.cookies))
+	for key, c := range j.cookies {
+		if c.IsExpired(now) {
+			delete(j.cookies, key)
+		} else if len(domain) == 0 || domainMatch(c.Domain, domain) {
+			list = append(list, *c)
+		}
+	}
+	j.mutex.Unlock()
+
+	sort.Slice(list, func(i, k int) bool {
+		if list[i].Domain != list[k].Domain {
+			return list[i].Domain < list[k].Domain
+		}
+		if list[i].Path != list[k].Path {
+			return list[i].Path < list[k].Path
+		}
+		return list[i].Name < list[k].Name
+	})
+	return list
+}
+
+// Clear -- remove the cookies of a domain and its subdomains or all the
+// cookies for an empty domain; returns the number of cookies removed
+func (j *CookieJar) Clear(domain string) int {
+	domain = canonicalHost(strings.TrimPrefix(domain, "."))
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	count := 0
+	for key, c := range j.cookies {
+		if len(domain) == 0 || domainMatch(c.Domain, domain) {
+			delete(j.cookies, key)
+			count++
+		}
+	}
+	return count
+}
+
+// Export -- write the cookies of a domain (or all cookies) in the Netscape
+// cookies.txt format; returns the number of cookies written
+func (j *CookieJar) Export(w io.Writer, domain string) (int, error) {
+	list := j.List(domain)
+	if _, err := fmt.Fprintf(w, "%s\n# Exported by restshell\n\n", NetscapeCookieHeader); err != nil {
+		return 0, err
+	}
+	for _, c := range list {
+		name := c.Domain
+		subdomains := "FALSE"
+		if !c.HostOnly {
+			name = "." + name
+			subdomains = "TRUE"
+		}
+		if c.HttpOnly {
+			name = httpOnlyPrefix + name
+		}
+		var expires int64
+		if !c.IsSession() {
+			expires = c.Expires.Unix()
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			name, subdomains, c.Path, strings.ToUpper(strconv.FormatBool(c.Secure)), expires, c.Name, c.Value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(list), nil
+}
+
+// Import -- add the cookies of a Netscape cookies.txt file to the jar;
+// expired cookies are skipped. Returns the number of cookies added.
+func (j *CookieJar) Import(r io.Reader) (int, error) {
+	now := time.Now()
+	cookies := make([]*JarCookie, 0)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(text, httpOnlyPrefix)
+		if httpOnly {
+			text = strings.TrimPrefix(text, httpOnlyPrefix)
+		} else if len(strings.TrimSpace(text)) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		c, err := parseNetscapeCookie(text)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		c.HttpOnly = httpOnly
+		if !c.IsExpired(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	j.add(cookies)
+	return len(cookies), nil
+}
+
+// add -- add cookies to the jar replacing the cookies with the same domain,
+// path and name
+func (j *CookieJar) add(cookies []*JarCookie) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, c := range cookies {
+		key := c.key()
+		if existing, ok := j.cookies[key]; ok {
+			c.created = existing.created
+		} else {
+			j.created++
+			c.created = j.created
+		}
+		j.cookies[key] = c
+	}
+}
+
+// parseNetscapeCookie -- parse the tab separated fields of a cookie: domain,
+// subdomains, path, secure, expires, name and value
+func parseNetscapeCookie(text string) (*JarCookie, error) {
+	fields := strings.Split(text, "\t")
+	if len(fields) == 6 {
+		fields = append(fields, "")
+	}
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("expected 7 tab separated fields but found %d", len(fields))
+	}
+
+	c := &JarCookie{
+		Domain: canonicalHost(strings.TrimPrefix(fields[0], ".")),
+		Path:   fields[2],
+		Name:   fields[5],
+		Value:  fields[6],
+	}
+	if len(c.Domain) == 0 || len(c.Name) == 0 {
+		return nil, fmt.Errorf("a cookie requires a domain and a name")
+	}
+	if len(c.Path) == 0 {
+		c.Path = "/"
+	}
+
+	subdomains, err := strconv.ParseBool(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid subdomain flag: %s", fields[1])
+	}
+	c.HostOnly = !subdomains
+	if c.Secure, err = strconv.ParseBool(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid secure flag: %s", fields[3])
+	}
+	expires, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry: %s", fields[4])
+	}
+	if expires > 0 {
+		c.Expires = time.Unix(expires, 0)
+	}
+	return c, nil
+}
+
+func canonicalHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// domainMatch -- true when a host is the domain or one of its subdomains
+func domainMatch(host string, domain string) bool {
+	return host == domain || (strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil)
+}
+
+// pathMatch -- true when the path of a request is the cookie path or below
+func pathMatch(path string, cookiePath string) bool {
+	if path == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(path, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || path[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath -- the directory of a request path
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if len(path) == 0 || path[0] != '/' || i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
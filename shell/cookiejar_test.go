@@ -0,0 +1,138 @@
+package shell
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func verifyJarCookies(t *testing.T, jar *CookieJar, target string, expected string) {
+	t.Helper()
+	u, _ := url.Parse(target)
+	names := make([]string, 0)
+	for _, c := range jar.Cookies(u) {
+		names = append(names, c.Name+"="+c.Value)
+	}
+	if actual := strings.Join(names, "; "); actual != expected {
+		t.Errorf("expected cookies %q for %s but got %q", expected, target, actual)
+	}
+}
+
+func TestCookieJarMatchesDomainPathAndSecure(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://www.example.com/api/books")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "api", Value: "4", Path: "/api/books"},
+		{Name: "other", Value: "5", Domain: "other.com"},
+		{Name: "suffix", Value: "6", Domain: "com"},
+	})
+
+	verifyJarCookies(t, jar, "https://www.example.com/api/books/1", "api=4; host=1; domain=2; secure=3")
+	verifyJarCookies(t, jar, "http://www.example.com/api/", "host=1; domain=2")
+	verifyJarCookies(t, jar, "http://www.example.com/apibooks", "domain=2")
+	verifyJarCookies(t, jar, "http://shop.example.com/api/", "domain=2")
+	verifyJarCookies(t, jar, "http://example.com/", "domain=2")
+	verifyJarCookies(t, jar, "http://other.com/", "")
+
+	if list := jar.List("shop.example.com"); len(list) != 0 {
+		t.Errorf("expected no cookies of a subdomain without cookies but got %v", list)
+	}
+	if list := jar.List("example.com"); len(list) != 4 {
+		t.Errorf("expected 4 cookies but got %v", list)
+	}
+}
+
+func TestCookieJarExpiry(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("http://example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "a", Value: "1", MaxAge: 60},
+		{Name: "b", Value: "2", Expires: time.Now().Add(time.Hour)},
+		{Name: "c", Value: "3", Expires: time.Now().Add(-time.Hour)},
+	})
+	verifyJarCookies(t, jar, "http://example.com/", "a=1; b=2")
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "", MaxAge: -1}, {Name: "b", Value: "new"}})
+	verifyJarCookies(t, jar, "http://example.com/", "b=new")
+	if list := jar.List(""); len(list) != 1 || !list[0].IsSession() {
+		t.Errorf("expected the replaced cookie to be a session cookie: %v", list)
+	}
+}
+
+func TestCookieJarExportImport(t *testing.T) {
+	jar := NewCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "abc", HttpOnly: true, Secure: true},
+		{Name: "pref", Value: "dark", Domain: "example.com", Path: "/app", Expires: expires},
+	})
+
+	var buf bytes.Buffer
+	if count, err := jar.Export(&buf, ""); err != nil || count != 2 {
+		t.Fatalf("export failed: %d %v", count, err)
+	}
+	expected := "#HttpOnly_example.com\tFALSE\t/\tTRUE\t0\tsession\tabc\n"
+	if !strings.HasPrefix(buf.String(), NetscapeCookieHeader) || !strings.Contains(buf.String(), expected) {
+		t.Errorf("unexpected export:\n%s", buf.String())
+	}
+
+	imported := NewCookieJar()
+	text := buf.String() + ".old.com\tTRUE\t/\tFALSE\t1\texpired\tx\r\n"
+	if count, err := imported.Import(strings.NewReader(text)); err != nil || count != 2 {
+		t.Fatalf("import failed: %d %v", count, err)
+	}
+	actual := imported.List("")
+	if len(actual) != 2 || actual[1].Name != "pref" || !actual[1].Expires.Equal(expires) || actual[1].HostOnly ||
+		actual[0] != (JarCookie{Name: "session", Value: "abc", Domain: "example.com", Path: "/",
+			HostOnly: true, Secure: true, HttpOnly: true, created: actual[0].created}) {
+		t.Errorf("unexpected import: %v", actual)
+	}
+
+	if _, err := imported.Import(strings.NewReader("example.com\tTRUE\t/\n")); err == nil {
+		t.Errorf("expected an error for an invalid line")
+	}
+	if count := imported.Clear("example.com"); count != 2 || len(imported.List("")) != 0 {
+		t.Errorf("expected the cookies to be cleared")
+	}
+}
+
+func TestRestClientUsesSessionJar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+			return
+		}
+		if c, err := r.Cookie("session"); err == nil {
+			w.Write([]byte(c.Value))
+		}
+	}))
+	defer server.Close()
+	defer GetCookieJar().Clear("")
+
+	set := NewCmdSet()
+	saved := globalOptions
+	defer func() { globalOptions = saved }()
+	ClearCmdOptions()
+	AddCommonCmdOptions(set, CmdRestclient)
+
+	CmdParse(set, []string{"get"})
+	client := NewRestClientFromOptions()
+	client.DoMethod(http.MethodGet, nil, server.URL+"/login")
+	if resp, err := client.DoMethod(http.MethodGet, nil, server.URL+"/books"); err != nil || resp.Text != "s1" {
+		t.Errorf("expected the session cookie to be sent: %v", err)
+	}
+
+	CmdParse(set, []string{"get", "--no-jar"})
+	client = NewRestClientFromOptions()
+	if resp, err := client.DoMethod(http.MethodGet, nil, server.URL+"/books"); err != nil || resp.Text != "" {
+		t.Errorf("expected no cookies with --no-jar: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// stepMode -- when the debugger stops before executing the next line
+type stepMode int
+
+const (
+	stepNone stepMode = iota // Stop only at breakpoints
+	stepInto                 // Stop at the next line
+	stepOver                 // Stop at the next line outside of nested scripts and procedures
+	stepOut                  // Stop at the next line of the calling script or procedure
+)
+
+// debugState -- state of the script debugger shared by nested scripts and
+// procedures; the debug session ends when the outermost script completes
+type debugState struct {
+	mode        stepMode
+	depth       int      // Call depth when stepping over or out
+	breakpoints []string // file:line locations or command names
+}
+
+var scriptDebugger = debugState{}
+
+// Support for unittesting
+var debugInput = bufio.NewReader(os.Stdin)
+var isInteractive = func() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// startStepping -- single step the next line executed by a script
+func startStepping() {
+	if isInteractive() {
+		scriptDebugger.mode = stepInto
+	}
+}
+
+// AddBreakpoint -- stop the debugger at a file:line location or before a command
+func AddBreakpoint(breakpoint string) error {
+	breakpoint = strings.TrimSpace(breakpoint)
+	if len(breakpoint) == 0 {
+		return errors.New("invalid breakpoint")
+	}
+	if !isLocationBreakpoint(breakpoint) {
+		breakpoint = strings.ToUpper(breakpoint)
+	}
+	for _, v := range scriptDebugger.breakpoints {
+		if v == breakpoint {
+			return nil
+		}
+	}
+	scriptDebugger.breakpoints = append(scriptDebugger.breakpoints, breakpoint)
+	return nil
+}
+
+// RemoveBreakpoint -- remove a breakpoint; returns false if not found
+func RemoveBreakpoint(breakpoint string) bool {
+	breakpoint = strings.TrimSpace(breakpoint)
+	for i, v := range scriptDebugger.breakpoints {
+		if strings.EqualFold(v, breakpoint) {
+			scriptDebugger.breakpoints = append(scriptDebugger.breakpoints[:i], scriptDebugger.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// endDebugSession -- reset the debugger when the outermost script completes
+func endDebugSession() {
+	scriptDebugger = debugState{}
+}
+
+func isLocationBreakpoint(breakpoint string) bool {
+	i := strings.LastIndex(breakpoint, ":")
+	if i < 0 {
+		return false
+	}
+	_, err := strconv.Atoi(breakpoint[i+1:])
+	return err == nil
+}
+
+// isBreakpoint -- true if a breakpoint matches the command or the location of
+// the line; locations match the full path or the trailing path elements
+func (d *debugState) isBreakpoint(line scriptLine, command string) bool {
+	location := line.Location()
+	for _, v := range d.breakpoints {
+		if isLocationBreakpoint(v) {
+			if len(location) > 0 && (location == v || strings.HasSuffix(location, "/"+v) || strings.HasSuffix(location, "\\"+v)) {
+				return true
+			}
+		} else if v == command {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldStop -- true if the debugger stops before executing the line of a script
+func (d *debugState) shouldStop(line scriptLine, command string) bool {
+	depth := len(callStack)
+	if depth == 0 {
+		return false
+	}
+
+	switch {
+	case d.mode == stepInto:
+		return true
+	case d.mode == stepOver && depth <= d.depth:
+		return true
+	case d.mode == stepOut && depth < d.depth:
+		return true
+	}
+	return d.isBreakpoint(line, command)
+}
+
+// isDebugExempt -- commands the debugger does not stop for (e.g. REM)
+func isDebugExempt(cmd interface{}) bool {
+	flow, ok := cmd.(FlowControl)
+	return ok && flow.RequestNoStep()
+}
+
+// prompt -- display the line executing and read debugger commands until
+// execution continues; returns a FlowQuit error to quit the script
+func (d *debugState) prompt() error {
+	w := ConsoleWriter()
+	line := callStack[len(callStack)-1].line
+	if location := line.Location(); len(location) > 0 {
+		fmt.Fprintf(w, "Stopped at %s: %s\n", location, strings.TrimSpace(line.Text))
+	} else {
+		fmt.Fprintf(w, "Stopped: %s\n", strings.TrimSpace(line.Text))
+	}
+
+	for {
+		fmt.Fprint(w, "Stopped> ")
+		input, err := debugInput.ReadString('\n')
+		if err != nil && len(input) == 0 {
+			// No more input; continue without the debugger
+			fmt.Fprintln(w)
+			d.mode = stepNone
+			return nil
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToLower(command) {
+		case "", "s", "step":
+			d.mode = stepInto
+			return nil
+		case "n", "next":
+			d.mode, d.depth = stepOver, len(callStack)
+			return nil
+		case "o", "out":
+			d.mode, d.depth = stepOut, len(callStack)
+			return nil
+		case "g", "c", "continue":
+			d.mode = stepNone
+			return nil
+		case "q", "quit":
+			return NewFlowError("Quit requested", FlowQuit)
+		case "b", "break":
+			if len(arg) == 0 {
+				d.listBreakpoints(w)
+			} else if err := AddBreakpoint(arg); err != nil {
+				fmt.Fprintln(w, err.Error())
+			}
+		case "d", "delete":
+			if !RemoveBreakpoint(arg) {
+				fmt.Fprintf(w, "Breakpoint not found: %s\n", arg)
+			}
+		case "p", "print":
+			printDebugVariables(w, arg)
+		case "set":
+			if k, v, ok := strings.Cut(arg, "="); ok && IsValidKey(k) {
+				SetGlobal(k, v)
+			} else {
+				fmt.Fprintln(w, "Usage: set name=value")
+			}
+		case "h", "history":
+			printDebugHistory(w, arg)
+		case "eval":
+			if err := ExecuteCommandLine(arg); err != nil {
+				fmt.Fprintf(w, "Error: %s\n", err.Error())
+			}
+		case "w", "where":
+			WriteCallStack(w)
+		case "?", "help":
+			debugUsage(w)
+		default:
+			fmt.Fprintf(w, "Unknown debugger command: %s (? for help)\n", command)
+		}
+	}
+}
+
+func (d *debugState) listBreakpoints(w io.Writer) {
+	if len(d.breakpoints) == 0 {
+		fmt.Fprintln(w, "No breakpoints")
+		return
+	}
+	for _, v := range d.breakpoints {
+		fmt.Fprintln(w, v)
+	}
+}
+
+// printDebugVariables -- print a variable or the variables starting with a prefix ending in *
+func printDebugVariables(w io.Writer, name string) {
+	if len(name) == 0 || strings.HasSuffix(name, "*") {
+		prefix := strings.TrimSuffix(name, "*")
+		EnumerateGlobals(func(k string, v interface{}) {
+			fmt.Fprintf(w, "%s=%s\n", k, formatDebugValue(v))
+		}, func(k string, v interface{}) bool {
+			return strings.HasPrefix(k, prefix)
+		})
+		return
+	}
+
+	if v := GetGlobal(name); v != nil {
+		fmt.Fprintf(w, "%s=%s\n", name, formatDebugValue(v))
+	} else {
+		fmt.Fprintf(w, "Variable not found: %s\n", name)
+	}
+}
+
+func formatDebugValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case Auth:
+		return fmt.Sprintf("Auth Context(%t)", t.IsAuthed())
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+// printDebugHistory -- print a result from the history (0 is the most recent)
+func printDebugHistory(w io.Writer, arg string) {
+	index := 0
+	if len(arg) > 0 {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			fmt.Fprintf(w, "Invalid history index: %s\n", arg)
+			return
+		}
+		index = n
+	}
+
+	result, err := PeekResult(index)
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+		return
+	}
+	if result.HttpStatus != 0 {
+		fmt.Fprintf(w, "HTTP Status: %s\n", result.HttpStatusString)
+	}
+	if result.Error != nil {
+		fmt.Fprintf(w, "Error: %s\n", result.Error.Error())
+	}
+	fmt.Fprintln(w, result.Text)
+}
+
+func debugUsage(w io.Writer) {
+	fmt.Fprintln(w, "Debugger commands:")
+	fmt.Fprintln(w, "  s, Enter       Step to the next line (into nested scripts and procedures)")
+	fmt.Fprintln(w, "  n              Step over nested scripts and procedures")
+	fmt.Fprintln(w, "  o              Step out to the calling script or procedure")
+	fmt.Fprintln(w, "  g, c           Continue to the next breakpoint")
+	fmt.Fprintln(w, "  q              Quit the script")
+	fmt.Fprintln(w, "  b [loc|cmd]    Add a breakpoint at file:line or a command; list breakpoints")
+	fmt.Fprintln(w, "  d loc|cmd      Delete a breakpoint")
+	fmt.Fprintln(w, "  p [var|pfx*]   Print variables")
+	fmt.Fprintln(w, "  set var=value  Change a variable")
+	fmt.Fprintln(w, "  h [n]          Show history result n (0 is the most recent)")
+	fmt.Fprintln(w, "  eval command   Execute a command without moving forward")
+	fmt.Fprintln(w, "  w              Show the call stack")
+}
@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runDebugScript -- run the outer script with the debugger reading the input
+// and return the recorded lines and the console output
+func runDebugScript(t *testing.T, options string, input string) ([]string, string) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "inner.rshell"), []byte("record inner\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "outer.rshell"), []byte("record one\nrun -s inner\nrecord two %%name%%\n"), 0644)
+
+	var console bytes.Buffer
+	savedConsole, savedInput, savedInteractive := currentConsole, debugInput, isInteractive
+	currentConsole = &console
+	debugInput = bufio.NewReader(strings.NewReader(input))
+	isInteractive = func() bool { return true }
+	defer func() {
+		currentConsole, debugInput, isInteractive = savedConsole, savedInput, savedInteractive
+	}()
+
+	recorded := runTestScript("run -s " + options + " " + filepath.Join(dir, "outer.rshell") + "\n")
+	return recorded, console.String()
+}
+
+func TestDebuggerStepOverNestedScript(t *testing.T) {
+	SetGlobal("name", "value")
+	recorded, output := runDebugScript(t, "--step", "n\nn\nn\n")
+	verifyRecorded(t, recorded, "one", "inner", "two value")
+	if strings.Count(output, "Stopped at") != 3 || strings.Contains(output, "inner.rshell:1") {
+		t.Errorf("expected to stop at each line of the outer script:\n%s", output)
+	}
+	if scriptDebugger.mode != stepNone {
+		t.Errorf("expected the debug session to end with the script")
+	}
+}
+
+func TestDebuggerStepIntoNestedScript(t *testing.T) {
+	recorded, output := runDebugScript(t, "--step", "\ns\ns\nq\n")
+	verifyRecorded(t, recorded, "one", "inner")
+	if !strings.Contains(output, "Stopped at inner.rshell:1: record inner\n") {
+		t.Errorf("expected to stop in the nested script:\n%s", output)
+	}
+}
+
+func TestDebuggerBreakpointInspectAndChange(t *testing.T) {
+	SetGlobal("name", "value")
+	PushResult(*NewTextResult("history text"))
+	input := "p name\nset name=changed\neval record eval\nh\nw\nc\n"
+	recorded, output := runDebugScript(t, "--break inner.rshell:1", input)
+	verifyRecorded(t, recorded, "one", "eval", "inner", "two changed")
+
+	expected := []string{
+		"Stopped at inner.rshell:1: record inner",
+		"name=value",
+		"history text",
+		"  at run inner.rshell (inner.rshell:1) record inner",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected %q in output:\n%s", line, output)
+		}
+	}
+	if strings.Count(output, "Stopped at") != 1 {
+		t.Errorf("expected to stop once at the breakpoint:\n%s", output)
+	}
+}
+
+func TestDebuggerCommandBreakpoint(t *testing.T) {
+	SetGlobal("name", "value")
+	recorded, output := runDebugScript(t, "--break run", "o\nc\n")
+	verifyRecorded(t, recorded, "one", "inner", "two value")
+	if !strings.Contains(output, "Stopped at ") || !strings.Contains(output, "outer.rshell:2: run -s inner\n") {
+		t.Errorf("expected to stop at the RUN command:\n%s", output)
+	}
+}
+
+func TestBreakpointMatching(t *testing.T) {
+	defer endDebugSession()
+	AddBreakpoint("get")
+	AddBreakpoint("lib.rshell:3")
+	line := scriptLine{Text: "x", File: "/scripts/lib.rshell", Line: 3}
+	if !scriptDebugger.isBreakpoint(line, "POST") {
+		t.Errorf("expected the location breakpoint to match")
+	}
+	if !scriptDebugger.isBreakpoint(scriptLine{}, "GET") {
+		t.Errorf("expected the command breakpoint to match")
+	}
+	if scriptDebugger.isBreakpoint(scriptLine{Text: "x", File: "/scripts/mylib.rshell", Line: 3}, "POST") {
+		t.Errorf("unexpected match of a different file")
+	}
+	if !RemoveBreakpoint("GET") || RemoveBreakpoint("GET") {
+		t.Errorf("expected the breakpoint to be removed once")
+	}
+}
@@ -0,0 +1,126 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Exit codes of the shell; the exit code is the LastError state of the last
+// command so an error handled by a script does not fail the shell
+const (
+	ExitSuccess         = 0   // The last command succeeded
+	ExitCommandFailed   = 1   // The last command failed
+	ExitAssertionFailed = 2   // An assertion failed (including ASSERT --exit aborts)
+	ExitNetworkError    = 3   // A request did not get a response
+	ExitAborted         = 4   // A flow abort was not handled (e.g. Ctrl-C)
+	ExitScriptError     = 5   // A script is invalid (e.g. parse error, unknown command or option)
+	ExitPanic           = 100 // A command failed with an exception
+)
+
+// exitCodeError -- an error with the exit code of the shell when the error
+// is the last error
+type exitCodeError struct {
+	err  error
+	code int
+}
+
+// NewExitCodeError -- associate an exit code with an error returned by a command
+func NewExitCodeError(err error, code int) error {
+	return exitCodeError{err: err, code: code}
+}
+
+func newScriptError(err error) error {
+	return NewExitCodeError(err, ExitScriptError)
+}
+
+func (e exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// GetExitCode -- the exit code for an error returned by a command
+func GetExitCode(err error) int {
+	var flow FlowError
+	if errors.As(err, &flow) && (flow.Cmd == FlowExit || flow.Cmd == FlowExitScript) {
+		return flow.code
+	}
+
+	var coded exitCodeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	if IsFlowControl(err, FlowAbort) {
+		return ExitAborted
+	}
+	return ExitCommandFailed
+}
+
+// isExitRequest -- true if the error is an EXIT of the shell or a script
+func isExitRequest(err error) bool {
+	return IsFlowControl(err, FlowExit) || IsFlowControl(err, FlowExitScript)
+}
+
+// ExitCommand -- end the shell or the current script with an exit code
+type ExitCommand struct {
+	scriptOption *bool
+}
+
+func NewExitCommand() *ExitCommand {
+	return &ExitCommand{}
+}
+
+func (cmd *ExitCommand) AddOptions(set CmdSet) {
+	set.SetParameters("[code]")
+	set.SetUsage(func() {
+		set.PrintUsage(ConsoleWriter())
+		cmd.ExtendedUsage(ConsoleWriter())
+	})
+	cmd.scriptOption = set.BoolLong("script", 0, "End the current script instead of the shell")
+	AddCommonCmdOptions(set, CmdDebug)
+}
+
+func (cmd *ExitCommand) ExtendedUsage(w io.Writer) {
+	fmt.Fprintf(w, "\nAdditional Information:\n")
+	fmt.Fprintf(w, "\nThe default code is the error state of the last command. Shell exit codes:\n")
+	fmt.Fprintf(w, "  %3d  success\n", ExitSuccess)
+	fmt.Fprintf(w, "  %3d  command failed\n", ExitCommandFailed)
+	fmt.Fprintf(w, "  %3d  assertion failed\n", ExitAssertionFailed)
+	fmt.Fprintf(w, "  %3d  network error\n", ExitNetworkError)
+	fmt.Fprintf(w, "  %3d  aborted\n", ExitAborted)
+	fmt.Fprintf(w, "  %3d  script error\n", ExitScriptError)
+	fmt.Fprintf(w, "  %3d  exception\n", ExitPanic)
+}
+
+func (cmd *ExitCommand) Execute(args []string) error {
+	if len(args) > 1 {
+		return ErrArguments
+	}
+
+	code := LastError
+	if len(args) == 1 {
+		value, err := strconv.Atoi(args[0])
+		if err != nil || value < 0 || value > 255 {
+			return fmt.Errorf("invalid exit code: %s", args[0])
+		}
+		code = value
+	}
+	return NewExitError(code, *cmd.scriptOption)
+}
+
+func (cmd *ExitCommand) DoNotCount() bool {
+	return true
+}
+
+func (cmd *ExitCommand) DoNotClearError() bool {
+	return true
+}
+
+func (cmd *ExitCommand) CommandCount() int {
+	return 0
+}
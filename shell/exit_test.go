@@ -0,0 +1,129 @@
+package shell
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestScript(t *testing.T, name string, script string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(script), 0644); err != nil {
+		t.Fatalf("unable to write script: %s", err.Error())
+	}
+	return file
+}
+
+func TestExitEndsScriptsAndShell(t *testing.T) {
+	file := writeTestScript(t, "exit.rshell", "record a\nexit 3\nrecord b\n")
+	script := `
+record start
+run ` + file + `
+record end
+`
+	verifyRecorded(t, runTestScript(script), "start", "a")
+	if LastError != 3 {
+		t.Errorf("expected exit code 3 but got %d", LastError)
+	}
+}
+
+func TestExitScriptEndsCurrentScript(t *testing.T) {
+	file := writeTestScript(t, "exit.rshell", `
+PROC leave
+  exit --script 4
+ENDPROC
+record a
+TRY
+  leave
+CATCH
+  record caught
+ENDTRY
+record b
+`)
+	verifyRecorded(t, runTestScript("run "+file+"\n"), "a")
+	if LastError != 4 {
+		t.Errorf("expected exit code 4 but got %d", LastError)
+	}
+
+	verifyRecorded(t, runTestScript("run "+file+"\nrecord after\n"), "a", "after")
+}
+
+func TestPanicSetsExitPanic(t *testing.T) {
+	verifyRecorded(t, runTestScript("record a\nrecord panic\n"), "a")
+	if LastError != ExitPanic {
+		t.Errorf("expected exit code %d but got %d", ExitPanic, LastError)
+	}
+}
+
+func TestExitDefaultsToLastError(t *testing.T) {
+	verifyRecorded(t, runTestScript("record fail\nexit\nrecord b\n"))
+	if LastError != ExitCommandFailed {
+		t.Errorf("expected exit code %d but got %d", ExitCommandFailed, LastError)
+	}
+
+	runTestScript("exit 256\n")
+	if LastError != ExitCommandFailed {
+		t.Errorf("expected an invalid exit code to fail the command")
+	}
+}
+
+func TestReturnFromScript(t *testing.T) {
+	file := writeTestScript(t, "return.rshell", `
+record a
+IF EQ --var record.count 1
+  RETURN the value
+ENDIF
+record b
+`)
+	SetGlobal("returntest", "unset")
+	verifyRecorded(t, runTestScript("run --result returntest "+file+"\nrecord %%returntest%%\n"), "a", "the value")
+
+	// Scripts without a RETURN do not change the result variable
+	file = writeTestScript(t, "noreturn.rshell", "record a\n")
+	verifyRecorded(t, runTestScript("run --result returntest "+file+"\nrecord %%returntest%%\n"), "a", "the value")
+}
+
+func TestScriptErrorExitCode(t *testing.T) {
+	runTestScript("nosuchcommand\n")
+	if LastError != ExitScriptError {
+		t.Errorf("expected exit code %d but got %d", ExitScriptError, LastError)
+	}
+
+	runTestScript("record --bogus\n")
+	if LastError != ExitScriptError {
+		t.Errorf("expected exit code %d but got %d", ExitScriptError, LastError)
+	}
+
+	runTestScript("record abort\n")
+	if LastError != ExitAborted {
+		t.Errorf("expected exit code %d but got %d", ExitAborted, LastError)
+	}
+}
+
+func TestGetExitCode(t *testing.T) {
+	assertAbort := NewExitCodeError(NewFlowError("failed", FlowAbort), ExitAssertionFailed)
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{errors.New("failed"), ExitCommandFailed},
+		{newScriptError(errors.New("bad")), ExitScriptError},
+		{NewFlowError("aborted", FlowAbort), ExitAborted},
+		{assertAbort, ExitAssertionFailed},
+		{NewExitCodeError(errors.New("no response"), ExitNetworkError), ExitNetworkError},
+		{NewExitError(7, false), 7},
+		{newCommandError("CMD", NewExitCodeError(errors.New("x"), 42), ""), 42},
+	}
+
+	for _, test := range tests {
+		if code := GetExitCode(test.err); code != test.expected {
+			t.Errorf("%s: expected %d but got %d", test.err.Error(), test.expected, code)
+		}
+	}
+
+	if !IsFlowControl(assertAbort, FlowAbort) {
+		t.Errorf("expected a wrapped abort to remain a flow abort")
+	}
+}
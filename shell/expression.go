@@ -0,0 +1,178 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PaesslerAG/gval"
+)
+
+// expressionLanguage -- built on first use and again after a substitution
+// function is registered
+var expressionLanguage *gval.Language
+var expressionLanguageMutex sync.Mutex
+
+// expressionCacheKey -- context key of the substitution function data cache
+type expressionCacheKey struct{}
+
+// getExpressionLanguage -- the gval language extended with the variables and
+// the registered substitution functions
+func getExpressionLanguage() gval.Language {
+	expressionLanguageMutex.Lock()
+	defer expressionLanguageMutex.Unlock()
+	if expressionLanguage == nil {
+		extensions := []gval.Language{
+			gval.VariableSelector(selectExpressionVariable),
+			gval.Function("var", func(name string) interface{} {
+				return GetGlobal(name)
+			}),
+		}
+		for name := range handlerMap {
+			extensions = append(extensions, gval.Function(name, makeExpressionFunction(name)))
+		}
+		language := gval.Full(extensions...)
+		expressionLanguage = &language
+	}
+	return *expressionLanguage
+}
+
+// resetExpressionLanguage -- rebuild the language with the registered
+// substitution functions on the next evaluation
+func resetExpressionLanguage() {
+	expressionLanguageMutex.Lock()
+	defer expressionLanguageMutex.Unlock()
+	expressionLanguage = nil
+}
+
+// selectExpressionVariable -- variables are referenced by name (e.g. book.id);
+// variables that are not set are nil
+func selectExpressionVariable(path gval.Evaluables) gval.Evaluable {
+	return func(c context.Context, v interface{}) (interface{}, error) {
+		keys, err := path.EvalStrings(c, v)
+		if err != nil {
+			return nil, err
+		}
+		return GetGlobal(strings.Join(keys, ".")), nil
+	}
+}
+
+// makeExpressionFunction -- call a substitution function from an expression
+// with the arguments of the substitution syntax: name([key, [fmt, [option]]])
+func makeExpressionFunction(name string) func(context.Context, ...interface{}) (interface{}, error) {
+	return func(c context.Context, args ...interface{}) (interface{}, error) {
+		if len(args) > 3 {
+			return nil, fmt.Errorf("too many arguments for %s", name)
+		}
+		params := make([]string, 3)
+		for i, arg := range args {
+			params[i] = FormatExpressionValue(arg)
+		}
+
+		cache, ok := c.Value(expressionCacheKey{}).(substitutionDataCache)
+		if !ok {
+			cache = make(substitutionDataCache)
+		}
+		value, ok := callSubstitutionFunction(cache, name, params[0], params[1], params[2])
+		if !ok {
+			return nil, fmt.Errorf("%s returned no value", name)
+		}
+		return value, nil
+	}
+}
+
+// EvaluateExpression -- evaluate an expression supporting arithmetic, string
+// and comparison operators, ternaries (a ? b : c), variables by name (or
+// var("name") for any name) and the registered substitution functions
+func EvaluateExpression(expression string) (interface{}, error) {
+	return evaluateExpression(expression, make(substitutionDataCache))
+}
+
+func evaluateExpression(expression string, cache substitutionDataCache) (interface{}, error) {
+	eval, err := getExpressionLanguage().NewEvaluable(expression)
+	if err != nil {
+		return nil, err
+	}
+	return eval(context.WithValue(context.Background(), expressionCacheKey{}, cache), nil)
+}
+
+// IsExpressionTrue -- true if the value of an expression is not false, zero,
+// empty or nil
+func IsExpressionTrue(value interface{}) bool {
+	switch t := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return len(t) > 0 && t != "0" && !strings.EqualFold(t, "false")
+	}
+	return true
+}
+
+// FormatExpressionValue -- the string value of an expression for substitution
+func FormatExpressionValue(value interface{}) string {
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(t)
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// performExpressionSubstitution -- replace the expressions of the input with
+// their values; expressions that fail are reported and left in the input.
+// Markers are paired left to right so %%a%%=%%b%% is not an expression.
+func performExpressionSubstitution(input string, cache substitutionDataCache) string {
+	if !strings.Contains(input, "%%=") {
+		return input
+	}
+
+	var result strings.Builder
+	for {
+		start := strings.Index(input, "%%")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(input[start+2:], "%%")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+
+		text := input[start+2 : end]
+		result.WriteString(input[:start])
+		input = input[end+2:]
+		if !strings.HasPrefix(text, "=") {
+			result.WriteString("%%" + text + "%%")
+			continue
+		}
+
+		expression := strings.TrimSpace(text[1:])
+		value, err := evaluateExpression(expression, cache)
+		if err != nil {
+			fmt.Fprintf(ErrorWriter(), "Expression error: %s: %s\n", expression, err.Error())
+			result.WriteString("%%" + text + "%%")
+			continue
+		}
+		result.WriteString(FormatExpressionValue(value))
+	}
+	result.WriteString(input)
+	return result.String()
+}
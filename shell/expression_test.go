@@ -0,0 +1,193 @@
+package shell
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var exprTestCounter = 0
+
+func init() {
+	RegisterSubstitutionHandler(SubstitutionFunction{
+		Name:         "exprcounter",
+		Group:        "exprtest",
+		FunctionHelp: "Test counter incremented for each key",
+		Function: func(cache interface{}, funcName string, format string, option string) (string, interface{}) {
+			value, ok := cache.(int)
+			if !ok {
+				exprTestCounter++
+				value = exprTestCounter
+			}
+			return strconv.Itoa(value) + format, value
+		},
+	})
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	SetGlobal("exprtest.num", "21")
+	SetGlobal("exprtest.str", "abc")
+	SetGlobal("$exprtest", "dollar")
+
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"exprtest.num * 2", "42"},
+		{"exprtest.num == 21", "true"},
+		{`exprtest.str + "def"`, "abcdef"},
+		{`exprtest.str == "abc" ? "yes" : "no"`, "yes"},
+		{`exprtest.str =~ "^a"`, "true"},
+		{"exprtest.num > 30 || exprtest.num < 10", "false"},
+		{`var("$exprtest")`, "dollar"},
+		{`exprtest.missing ?? "default"`, "default"},
+		{`exprcounter("", "x")`, strconv.Itoa(exprTestCounter+1) + "x"},
+	}
+
+	for _, test := range tests {
+		value, err := EvaluateExpression(test.expression)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.expression, err.Error())
+		} else if result := FormatExpressionValue(value); result != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.expression, test.expected, result)
+		}
+	}
+}
+
+func TestExpressionSubstitution(t *testing.T) {
+	SetGlobal("exprtest.num", "5")
+
+	result := PerformVariableSubstitution("value=%%= exprtest.num + 1 %% num=%%exprtest.num%%")
+	if result != "value=6 num=5" {
+		t.Errorf("unexpected substitution: %s", result)
+	}
+
+	result = PerformVariableSubstitution(`%%exprcounter(k)%% %%= exprcounter("k") %%`)
+	if parts := strings.Fields(result); len(parts) != 2 || parts[0] != parts[1] {
+		t.Errorf("expected keyed values to match: %s", result)
+	}
+
+	result = PerformVariableSubstitution("%%exprtest.num%%=%%exprtest.num%%")
+	if result != "5=5" {
+		t.Errorf("expected variables only: %s", result)
+	}
+}
+
+func TestExpressionSubstitutionError(t *testing.T) {
+	errors := &bytes.Buffer{}
+	saved := currentError
+	currentError = errors
+	defer func() { currentError = saved }()
+
+	result := PerformVariableSubstitution("value=%%= 1 + %%")
+	if result != "value=%%= 1 + %%" {
+		t.Errorf("expected the expression to remain: %s", result)
+	}
+	if !strings.Contains(errors.String(), "Expression error: 1 +") {
+		t.Errorf("expected an expression error: %s", errors.String())
+	}
+}
+
+func TestExpressionConditions(t *testing.T) {
+	SetGlobal("exprtest.num", "3")
+	script := `
+IF %%= exprtest.num > 2 && exprtest.num < 5 %%
+  record yes
+ELSE
+  record no
+ENDIF
+WHILE %%= record.count < 3 %%
+  record %%= record.count * 10 %%
+ENDWHILE
+`
+	verifyRecorded(t, runTestScript(script), "yes", "10", "20")
+}
+
+func TestExpressionValueConditions(t *testing.T) {
+	SetGlobal("exprtest.num", "3")
+	SetGlobal("exprtest.flag", "true")
+	script := `
+IF %%= exprtest.num %%
+  record number
+ENDIF
+IF %%= exprtest.num - 3 %%
+  record zero
+ENDIF
+IF %%exprtest.flag%%
+  record flag
+ENDIF
+IF FALSE
+  record false
+ENDIF
+`
+	verifyRecorded(t, runTestScript(script), "number", "flag")
+}
+
+func TestInvalidValueConditions(t *testing.T) {
+	SetGlobal("exprtest.name", "widget")
+	for _, condition := range []string{"LASTER", "%%exprtest.missing%%", "%%exprtest.name%%"} {
+		got := runTestScript("IF " + condition + "\n  record taken\nENDIF\n")
+		if len(got) != 0 || LastError == 0 {
+			t.Errorf("IF %s: expected an error but got %v", condition, got)
+		}
+	}
+
+	script := `
+IF 1
+  record one
+ENDIF
+IF True
+  record true
+ENDIF
+IF %%= exprtest.name %%
+  record expression
+ENDIF
+`
+	verifyRecorded(t, runTestScript(script), "one", "true", "expression")
+}
+
+func TestExpressionSeesLateRegistrations(t *testing.T) {
+	if _, err := EvaluateExpression("1 + 1"); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	RegisterSubstitutionHandler(SubstitutionFunction{
+		Name:         "exprlate",
+		Group:        "exprtest",
+		FunctionHelp: "Test function registered after the first evaluation",
+		Function: func(cache interface{}, funcName string, format string, option string) (string, interface{}) {
+			return "late", "late"
+		},
+	})
+	if value, err := EvaluateExpression(`exprlate() + "!"`); err != nil || value != "late!" {
+		t.Errorf("expected the function registered later but got %v (%v)", value, err)
+	}
+}
+
+func TestRunConditionExpression(t *testing.T) {
+	SetGlobal("exprtest.num", "3")
+	tests := []struct {
+		condition string
+		expected  bool
+	}{
+		{"", true},
+		{"exprtest.num", true},
+		{"exprtest.num=4", false},
+		{"=exprtest.num > 2", true},
+		{`=exprtest.num == 4 ? true : ""`, false},
+	}
+
+	for _, test := range tests {
+		met, err := isConditionMet(test.condition)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.condition, err.Error())
+		} else if met != test.expected {
+			t.Errorf("%s: expected %v but got %v", test.condition, test.expected, met)
+		}
+	}
+
+	if _, err := isConditionMet("=1 +"); err == nil {
+		t.Errorf("expected an error for an invalid expression")
+	}
+}
@@ -1,5 +1,7 @@
 package shell
 
+import "errors"
+
 // FlowControl - Special Command interfaces to control execution within the command processor
 type FlowControl interface {
 	RequestQuit() bool
@@ -27,8 +29,10 @@ func DoesCommandRequestNoStep(cmd interface{}) bool {
 type FlowErrorCmd string
 
 type FlowError struct {
-	Message string
-	Cmd     FlowErrorCmd
+	Message   string
+	Cmd       FlowErrorCmd
+	interrupt bool
+	code      int // Exit code of FlowExit and FlowExitScript
 }
 
 var (
@@ -38,11 +42,28 @@ var (
 	FlowAbort FlowErrorCmd = "a"
 	// FlowGo - Continue and exit single step mode
 	FlowGo FlowErrorCmd = "g"
+	// FlowExit - Terminate the scripts and the shell with an exit code
+	FlowExit FlowErrorCmd = "x"
+	// FlowExitScript - Terminate the current script with an exit code
+	FlowExitScript FlowErrorCmd = "e"
 )
 
 // NewFlowError - Return a FlowError which provides actions to cmd processor
 func NewFlowError(msg string, cmd FlowErrorCmd) error {
-	return FlowError{msg, cmd}
+	return FlowError{Message: msg, Cmd: cmd}
+}
+
+// NewInterruptError - Return a FlowAbort error for a command interrupted by the user
+func NewInterruptError() error {
+	return FlowError{Message: "Command interrupted", Cmd: FlowAbort, interrupt: true}
+}
+
+// NewExitError - Return a FlowExit (or FlowExitScript) error with the exit code
+func NewExitError(code int, script bool) error {
+	if script {
+		return FlowError{Message: "Exit script", Cmd: FlowExitScript, code: code}
+	}
+	return FlowError{Message: "Exit", Cmd: FlowExit, code: code}
 }
 
 // Error - Return the error message for a flow error
@@ -52,7 +73,17 @@ func (f FlowError) Error() string {
 
 // IsFlowControl - Determines if the error as the given action associated
 func IsFlowControl(err error, action FlowErrorCmd) bool {
-	if f, ok := err.(FlowError); ok && f.Cmd == action {
+	var f FlowError
+	if errors.As(err, &f) && f.Cmd == action {
+		return true
+	}
+	return false
+}
+
+// IsInterrupt - Determines if the error is an abort requested by the user (Ctrl-C)
+func IsInterrupt(err error) bool {
+	var f FlowError
+	if errors.As(err, &f) && f.interrupt {
 		return true
 	}
 	return false
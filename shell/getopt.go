@@ -53,6 +53,19 @@ func CmdParse(set CmdSet, tokens []string) error {
 	}
 }
 
+// CmdLongOptions -- the sorted long names of the options of a set
+func CmdLongOptions(set CmdSet) []string {
+	names := make([]string, 0)
+	if s, ok := set.(*newCmdSet); ok {
+		s.VisitAll(func(o getopt.Option) {
+			if len(o.LongName()) > 0 {
+				names = append(names, o.LongName())
+			}
+		})
+	}
+	return SortedStringSlice(names)
+}
+
 // StringListLong -- implement a string list option
 func (c *newCmdSet) StringListLong(name string, short rune, help ...string) *StringList {
 	initial := &StringList{
@@ -49,6 +49,7 @@ var supportedPrefixKeys = "$_#."
 
 func initGlobalStore() {
 	globalStore = make(map[string]interface{}, 0)
+	scopeStack = make([]*variableScope, 0)
 }
 
 func EnableGlobalOptions() {
@@ -59,7 +60,61 @@ func EnableGlobalOptions() {
 	displayHelp = getopt.BoolLong("help", 'h', "Display help")
 }
 
+// variableScope -- a local scope of variables for a script or procedure
+type variableScope struct {
+	name string
+	vars map[string]interface{}
+}
+
+// Local scopes are searched from the end of the stack to the global store.
+// A nil value in a local scope hides a variable of a parent scope.
+var scopeStack = make([]*variableScope, 0)
+
+// PushScope -- push a new local variable scope and return a function to
+// remove it
+func PushScope(name string) func() {
+	scopeStack = append(scopeStack, &variableScope{name: name, vars: make(map[string]interface{})})
+	depth := len(scopeStack)
+	return func() {
+		if len(scopeStack) >= depth {
+			scopeStack = scopeStack[:depth-1]
+		}
+	}
+}
+
+// findScope -- find the variables of the nearest scope containing the key;
+// the global store is returned if no local scope contains the key
+func findScope(key string) (map[string]interface{}, string) {
+	for i := len(scopeStack) - 1; i >= 0; i-- {
+		if _, ok := scopeStack[i].vars[key]; ok {
+			return scopeStack[i].vars, scopeStack[i].name
+		}
+	}
+	return globalStore, GlobalScopeName
+}
+
+// GlobalScopeName -- name of the global variable scope
+var GlobalScopeName = "global"
+
+// GetVariableScope -- get the name of the scope providing the value of a variable
+func GetVariableScope(key string) string {
+	_, name := findScope(key)
+	return name
+}
+
+// SetGlobal -- set a variable in the nearest scope containing the variable
+// or the global scope if the variable is not found
 func SetGlobal(key string, value interface{}) error {
+	if !IsValidKey(key) {
+		return ErrInvalidKey
+	}
+	vars, _ := findScope(key)
+	vars[key] = value
+	return nil
+}
+
+// SetGlobalScope -- set a variable in the global scope
+func SetGlobalScope(key string, value interface{}) error {
 	if !IsValidKey(key) {
 		return ErrInvalidKey
 	}
@@ -67,20 +122,42 @@ func SetGlobal(key string, value interface{}) error {
 	return nil
 }
 
+// SetLocal -- set a variable in the current local scope or the global
+// scope when no local scope exists
+func SetLocal(key string, value interface{}) error {
+	if !IsValidKey(key) {
+		return ErrInvalidKey
+	}
+	if len(scopeStack) == 0 {
+		globalStore[key] = value
+	} else {
+		scopeStack[len(scopeStack)-1].vars[key] = value
+	}
+	return nil
+}
+
+// HideLocal -- hide a variable of parent scopes in the current local scope
+func HideLocal(key string) {
+	if len(scopeStack) > 0 {
+		scopeStack[len(scopeStack)-1].vars[key] = nil
+	}
+}
+
 // Only set the global if not initialized already
 func InitializeGlobal(key string, value interface{}) error {
 	if !IsValidKey(key) {
 		return ErrInvalidKey
 	}
 
-	if _, ok := globalStore[key]; !ok {
-		globalStore[key] = value
+	if GetGlobal(key) == nil {
+		return SetGlobal(key, value)
 	}
 	return nil
 }
 
 func GetGlobal(key string) interface{} {
-	if v, ok := globalStore[key]; !ok {
+	vars, _ := findScope(key)
+	if v, ok := vars[key]; !ok {
 		return nil
 	} else {
 		return v
@@ -88,7 +165,7 @@ func GetGlobal(key string) interface{} {
 }
 
 func TryGetGlobalString(key string) (string, bool) {
-	if v, ok := globalStore[key]; !ok {
+	if v := GetGlobal(key); v == nil {
 		return "", false
 	} else {
 		switch t := v.(type) {
@@ -121,6 +198,28 @@ func GetGlobalStringWithFallback(key string, fallback string) string {
 	return v
 }
 
+// visibleVariables -- the variables visible from the current scope
+func visibleVariables() map[string]interface{} {
+	if len(scopeStack) == 0 {
+		return globalStore
+	}
+
+	vars := make(map[string]interface{}, len(globalStore))
+	for k, v := range globalStore {
+		vars[k] = v
+	}
+	for _, scope := range scopeStack {
+		for k, v := range scope.vars {
+			if v == nil {
+				delete(vars, k)
+			} else {
+				vars[k] = v
+			}
+		}
+	}
+	return vars
+}
+
 func EnumerateGlobals(fn func(key string, value interface{}), filter func(string, interface{}) bool) {
 	// Supports a best practice by separating "_" prefixed keys from others
 	var keys []string
@@ -128,7 +227,8 @@ func EnumerateGlobals(fn func(key string, value interface{}), filter func(string
 	var otherKeys []string
 
 	// Build list of keys to be sorted
-	for k := range globalStore {
+	vars := visibleVariables()
+	for k := range vars {
 		if strings.HasPrefix(k, "_") {
 			_keys = append(_keys, k)
 		} else if strings.Contains(supportedPrefixKeys, k[:1]) {
@@ -148,16 +248,18 @@ func EnumerateGlobals(fn func(key string, value interface{}), filter func(string
 	// Enumerate the keys and process the map values
 	for _, v := range keys {
 		if filter != nil {
-			if !filter(v, globalStore[v]) {
+			if !filter(v, vars[v]) {
 				continue
 			}
 		}
-		fn(v, globalStore[v])
+		fn(v, vars[v])
 	}
 }
 
+// RemoveGlobal -- remove a variable from the nearest scope containing it
 func RemoveGlobal(key string) {
-	delete(globalStore, key)
+	vars, _ := findScope(key)
+	delete(vars, key)
 }
 
 func IsValidKey(key string) bool {
@@ -0,0 +1,74 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestScopeLookupFallsThroughToParent(t *testing.T) {
+	SetGlobal("scope.parent", "global")
+	pop := PushScope("test")
+	defer pop()
+
+	if v := GetGlobalString("scope.parent"); v != "global" {
+		t.Errorf("expected the global value but got %s", v)
+	}
+
+	SetLocal("scope.parent", "local")
+	if v := GetGlobalString("scope.parent"); v != "local" {
+		t.Errorf("expected the local value but got %s", v)
+	}
+	if scope := GetVariableScope("scope.parent"); scope != "test" {
+		t.Errorf("expected the local scope but got %s", scope)
+	}
+
+	pop()
+	if v := GetGlobalString("scope.parent"); v != "global" {
+		t.Errorf("expected the global value after pop but got %s", v)
+	}
+}
+
+func TestSetGlobalUpdatesNearestScope(t *testing.T) {
+	RemoveGlobal("scope.var")
+	RemoveGlobal("scope.new")
+	defer PushScope("outer")()
+	SetLocal("scope.var", "outer")
+	defer PushScope("inner")()
+
+	SetGlobal("scope.var", "updated")
+	SetGlobal("scope.new", "created")
+	if scope := GetVariableScope("scope.var"); scope != "outer" {
+		t.Errorf("expected the variable to remain in the outer scope but got %s", scope)
+	}
+	if scope := GetVariableScope("scope.new"); scope != GlobalScopeName {
+		t.Errorf("expected a new variable in the global scope but got %s", scope)
+	}
+
+	SetGlobalScope("scope.var", "global")
+	if v := GetGlobalString("scope.var"); v != "updated" {
+		t.Errorf("expected the local value to hide the global value but got %s", v)
+	}
+	RemoveGlobal("scope.new")
+}
+
+func TestHiddenVariablesAreNotVisible(t *testing.T) {
+	SetGlobal("scope.hidden", "global")
+	defer RemoveGlobal("scope.hidden")
+	defer PushScope("test")()
+	HideLocal("scope.hidden")
+
+	if GetGlobal("scope.hidden") != nil {
+		t.Errorf("expected the variable to be hidden")
+	}
+
+	found := false
+	EnumerateGlobals(func(k string, v interface{}) { found = true }, func(k string, v interface{}) bool {
+		return k == "scope.hidden"
+	})
+	if found {
+		t.Errorf("expected the hidden variable not to be enumerated")
+	}
+
+	if PerformVariableSubstitution("%%scope.hidden%%") != "%%scope.hidden%%" {
+		t.Errorf("expected the hidden variable not to be substituted")
+	}
+}
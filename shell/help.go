@@ -34,6 +34,13 @@ For more information consult the repository README.md file
 		}
 	}
 
+	if procs := GetAllProcNames(); len(procs) > 0 {
+		fmt.Fprintf(ConsoleWriter(), "\nProcedures:\n")
+		for _, proc := range ColumnizeTokens(procs, 5, 12) {
+			fmt.Fprintf(ConsoleWriter(), "  %s\n", proc)
+		}
+	}
+
 	var finaltext = `Command modifiers when prefixing command:
 
 #  Comment character to ignore the content on the line (must be first)
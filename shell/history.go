@@ -2,6 +2,7 @@ package shell
 
 import (
 	"encoding/base64"
+	"math"
 	"reflect"
 	"strconv"
 	"time"
@@ -53,6 +54,7 @@ type HistoryOptions struct {
 	valueIsAuthPath   *bool
 	valueIsCookiePath *bool
 	valueIsHeaderPath *bool
+	valueIsTimingPath *bool
 	valueIsHttpStatus *bool
 }
 
@@ -74,6 +76,9 @@ func AddHistoryOptions(set CmdSet, payloadType ...ResultPayloadType) HistoryOpti
 	if isHistoryOptionsRequested(HeaderPath, payloadType) {
 		options.valueIsHeaderPath = set.BoolLong("path-header", 0, "Use path/value to reference Header value in history")
 	}
+	if isHistoryOptionsRequested(TimingPath, payloadType) {
+		options.valueIsTimingPath = set.BoolLong("path-timing", 0, "Use path/value to reference request timing (ms) in history")
+	}
 
 	return options
 }
@@ -98,6 +103,12 @@ func (ho HistoryOptions) IsHeaderPath() bool {
 	return ho.valueIsHeaderPath != nil && *ho.valueIsHeaderPath
 }
 
+// IsTimingPath -- Is the timing path option selected to reference the
+// duration of the request phases
+func (ho HistoryOptions) IsTimingPath() bool {
+	return ho.valueIsTimingPath != nil && *ho.valueIsTimingPath
+}
+
 // IsHeaderPath -- Is the history path option selected
 func (ho HistoryOptions) IsHttpStatusPath() bool {
 	return ho.valueIsHttpStatus != nil && *ho.valueIsHttpStatus
@@ -105,7 +116,7 @@ func (ho HistoryOptions) IsHttpStatusPath() bool {
 
 // IsPathOptionEnabled -- True if any history path option is enabled
 func (ho HistoryOptions) IsHistoryPathOptionEnabled() bool {
-	if ho.IsResultPathOption() || ho.IsAuthPath() || ho.IsCookiePath() || ho.IsHeaderPath() || ho.IsHttpStatusPath() {
+	if ho.IsResultPathOption() || ho.IsAuthPath() || ho.IsCookiePath() || ho.IsHeaderPath() || ho.IsTimingPath() || ho.IsHttpStatusPath() {
 		return true
 	}
 	return false
@@ -132,6 +143,10 @@ func (ho *HistoryOptions) SetPathOption(payloadType ResultPayloadType) {
 		if ho.valueIsHeaderPath != nil {
 			*ho.valueIsHeaderPath = true
 		}
+	case TimingPath:
+		if ho.valueIsTimingPath != nil {
+			*ho.valueIsTimingPath = true
+		}
 	}
 }
 
@@ -148,6 +163,9 @@ func (ho *HistoryOptions) ClearPathOptions() {
 	if ho.valueIsHeaderPath != nil {
 		*ho.valueIsHeaderPath = false
 	}
+	if ho.valueIsTimingPath != nil {
+		*ho.valueIsTimingPath = false
+	}
 }
 
 func (ho HistoryOptions) GetNodeFromHistory(index int, path string) (interface{}, error) {
@@ -167,6 +185,11 @@ func (ho HistoryOptions) GetNode(path string, result Result) (interface{}, error
 		return result.CookieMap.GetNode(path)
 	} else if ho.IsHeaderPath() {
 		return result.HeaderMap.GetNode(path)
+	} else if ho.IsTimingPath() {
+		if result.TimingMap == nil {
+			return nil, ErrNotFound
+		}
+		return result.TimingMap.GetNode(path)
 	} else {
 		return result.BodyMap.GetNode(path)
 	}
@@ -201,24 +224,46 @@ func isHistoryOptionsRequested(t ResultPayloadType, list []ResultPayloadType) bo
 
 // PushResponse -- Push a RestResponse into the history buffer
 func PushResponse(resp *RestResponse, resperror error) error {
-	var result Result
-	{
-		if err := result.addCookieMap(resp); err != nil {
-			fmt.Fprintf(ConsoleWriter(), "WARNING: parsing cookies returned: %s", err.Error())
-		}
+	result := newResponseResult(resp, resperror)
+	result.addParsedContentToResult(resp.GetContentType(), resp.Text)
+	return pushResponseResult(result, resperror)
+}
 
-		if err := result.addHeaderMap(resp); err != nil {
-			fmt.Fprintf(ConsoleWriter(), "WARNING: parsing header returned: %s", err.Error())
-		}
+// PushDownload -- Push the response of a download saved to a file into the
+// history buffer with the information of the content
+func PushDownload(resp *RestResponse, info ContentInfo, resperror error) error {
+	result := newResponseResult(resp, resperror)
+	result.ContentType = resp.GetContentType()
+	result.setContentInfo(info)
+	return pushResponseResult(result, resperror)
+}
 
-		result.Text = resp.Text
-		result.Error = resperror
-		result.HttpStatus = resp.GetStatus()
-		result.HttpStatusString = resp.GetStatusString()
-		result.addParsedContentToResult(resp.GetContentType(), resp.Text)
+func newResponseResult(resp *RestResponse, resperror error) Result {
+	var result Result
+	if err := result.addCookieMap(resp); err != nil {
+		fmt.Fprintf(ConsoleWriter(), "WARNING: parsing cookies returned: %s", err.Error())
+	}
+
+	if err := result.addHeaderMap(resp); err != nil {
+		fmt.Fprintf(ConsoleWriter(), "WARNING: parsing header returned: %s", err.Error())
 	}
 
+	result.Text = resp.Text
+	result.Error = resperror
+	result.HttpStatus = resp.GetStatus()
+	result.HttpStatusString = resp.GetStatusString()
+	result.Attempts = resp.GetAttempts()
+	result.setTiming(resp.GetTiming())
+	return result
+}
+
+func pushResponseResult(result Result, resperror error) error {
+	// The result is recorded when a hook fails so it can be examined
+	hookErr := runResultHooks(&result)
 	PushResult(result)
+	if hookErr != nil {
+		return hookErr
+	}
 	return resperror
 }
 
@@ -299,6 +344,73 @@ func ConvertNodeValueToString(node interface{}) (string, error) {
 	}
 }
 
+// CompareNodeValue -- compare a node value with a string value converted to the
+// type of the node; returns 0 when equal, +1 when the node is greater (or nil)
+// and -1 when the node is less
+func CompareNodeValue(i interface{}, value string) (int, error) {
+	if i == nil {
+		return +1, nil
+	}
+
+	switch t := i.(type) {
+	case string:
+		return strings.Compare(t, value), nil
+	case float64:
+		numValue, err := strconv.ParseFloat(value, 64)
+		if IsCmdDebugEnabled() {
+			fmt.Fprintf(ConsoleWriter(), "Debug: nodeValue: %f value: %f\n", t, numValue)
+		}
+		if err != nil {
+			return 0, ErrInvalidValue
+		}
+		if math.Abs((t - numValue)) < .00001 {
+			return 0, nil
+		} else if t > numValue {
+			return +1, nil
+		} else {
+			return -1, nil
+		}
+	case int:
+		numValue, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, err
+		}
+		if t == numValue {
+			return 0, nil
+		} else if t > numValue {
+			return +1, nil
+		} else {
+			return -1, nil
+		}
+	case int64:
+		numValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if t == numValue {
+			return 0, nil
+		} else if t > numValue {
+			return +1, nil
+		} else {
+			return -1, nil
+		}
+	case bool:
+		boolval, err := strconv.ParseBool(value)
+		if err != nil {
+			return -1, err
+		}
+		if t == boolval {
+			return 0, nil
+		} else if boolval == false {
+			return +1, nil
+		} else {
+			return -1, nil
+		}
+	default:
+		return 0, errors.New(ErrUnexpectedType.Error() + ": " + reflect.TypeOf(i).String())
+	}
+}
+
 // GetValueAsDate -- given a scaler value in an interface convert it
 // to a date if it is can be converted
 func GetValueAsDate(i interface{}) (time.Time, error) {
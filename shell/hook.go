@@ -0,0 +1,298 @@
+// Hooks
+//
+// Hooks run before and after every command and HTTP request. A hook is a Go
+// function registered with RegisterHook or an alias registered by a script
+// with the HOOK command:
+//
+//	hook add pre-request addcorrelation
+//
+// Script hooks run with the local variables hook.event, hook.command,
+// hook.error, hook.method, hook.url and hook.status describing the command or
+// request. A pre-request hook changes the outgoing headers with HOOK HEADER
+// and the result of a post-request hook is the latest history result while
+// the hook runs. Hooks are not run for the commands and requests of a hook.
+//
+// The jobs of benchmarks run on concurrent workers: Go hooks run for the
+// requests of the workers at the same time and must be safe for concurrent
+// use, while script hooks change the state of the shell and are not run for
+// the requests of the workers.
+
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// HookEvent -- a point where hooks are run
+type HookEvent string
+
+// Hook events
+const (
+	HookPreCommand  HookEvent = "pre-command"  // Before a command is executed
+	HookPostCommand HookEvent = "post-command" // After a command is executed
+	HookPreRequest  HookEvent = "pre-request"  // Before an HTTP request is sent
+	HookPostRequest HookEvent = "post-request" // Before the response of a request is recorded in the history
+)
+
+var hookEvents = []HookEvent{HookPreCommand, HookPostCommand, HookPreRequest, HookPostRequest}
+
+// HookContext -- the command or request a hook is run for
+type HookContext struct {
+	Event   HookEvent
+	Command string        // Command of command hooks
+	Args    []string      // Arguments of the command
+	Error   error         // Error returned by the command (post-command)
+	Request *http.Request // Outgoing request (pre-request); the headers may be changed
+	Result  *Result       // Result to be recorded (post-request); may be changed
+}
+
+// HookFunc -- a hook registered from Go; an error fails the command or request
+type HookFunc func(ctx *HookContext) error
+
+// hook -- a registered Go function or script alias
+type hook struct {
+	name  string
+	fn    HookFunc
+	alias string
+}
+
+var hookStore = make(map[HookEvent][]hook)
+var runningHook *HookContext // The context of the hook running on the shell; nil when no hook is running
+var concurrentJobs int32     // Number of job processors running workers
+
+// RegisterHook -- register a Go function run at a hook event; hooks run in
+// the order they are registered
+func RegisterHook(event HookEvent, name string, fn HookFunc) error {
+	if fn == nil {
+		return errors.New("hook function is required")
+	}
+	return addHook(event, hook{name: name, fn: fn})
+}
+
+// RegisterScriptHook -- register an alias run at a hook event
+func RegisterScriptHook(event HookEvent, alias string) error {
+	if _, err := GetAlias(alias); err != nil {
+		return fmt.Errorf("alias not found: %s", alias)
+	}
+	alias = strings.ToUpper(alias)
+	return addHook(event, hook{name: alias, alias: alias})
+}
+
+func addHook(event HookEvent, h hook) error {
+	if !isHookEvent(event) {
+		return fmt.Errorf("invalid hook event: %s", event)
+	}
+	if len(h.name) == 0 {
+		return errors.New("hook name is required")
+	}
+	for _, existing := range hookStore[event] {
+		if strings.EqualFold(existing.name, h.name) {
+			return fmt.Errorf("hook already registered: %s", h.name)
+		}
+	}
+	hookStore[event] = append(hookStore[event], h)
+	return nil
+}
+
+// RemoveHook -- remove a hook from an event
+func RemoveHook(event HookEvent, name string) error {
+	hooks := hookStore[event]
+	for i, h := range hooks {
+		if strings.EqualFold(h.name, name) {
+			hookStore[event] = append(hooks[:i:i], hooks[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("hook not found: %s", name)
+}
+
+func isHookEvent(event HookEvent) bool {
+	for _, e := range hookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks -- run the hooks of the event of a context; the first error stops
+// the hooks. Hooks are not run while a hook is running. The workers of a job
+// processor only run the Go hooks and do not change the state of the shell.
+func runHooks(ctx *HookContext) error {
+	hooks := hookStore[ctx.Event]
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	concurrent := atomic.LoadInt32(&concurrentJobs) > 0
+	if !concurrent {
+		if runningHook != nil {
+			return nil
+		}
+		runningHook = ctx
+		defer func() { runningHook = nil }()
+	}
+	for _, h := range hooks {
+		var err error
+		if h.fn != nil {
+			err = h.fn(ctx)
+		} else if !concurrent {
+			err = runScriptHook(h.alias, ctx)
+		}
+		if err != nil {
+			return NewExitCodeError(fmt.Errorf("%s hook %s: %w", ctx.Event, h.name, err), GetExitCode(err))
+		}
+	}
+	return nil
+}
+
+// runCommandHooks -- run the hooks of a command
+func runCommandHooks(event HookEvent, tokens []string, cmdErr error) error {
+	return runHooks(&HookContext{Event: event, Command: tokens[0], Args: tokens[1:], Error: cmdErr})
+}
+
+// RunRequestHooks -- run the pre-request hooks of an outgoing request
+func RunRequestHooks(req *http.Request) error {
+	return runHooks(&HookContext{Event: HookPreRequest, Request: req})
+}
+
+// runResultHooks -- run the post-request hooks of a result to be recorded
+func runResultHooks(result *Result) error {
+	return runHooks(&HookContext{Event: HookPostRequest, Result: result})
+}
+
+// runScriptHook -- run the alias of a script hook in a local scope; a hook
+// fails when its last command fails
+func runScriptHook(alias string, ctx *HookContext) error {
+	defer PushScope("hook:" + string(ctx.Event))()
+	SetLocal("hook.event", string(ctx.Event))
+	if len(ctx.Command) > 0 {
+		SetLocal("hook.command", strings.Join(append([]string{ctx.Command}, ctx.Args...), " "))
+	}
+	if ctx.Error != nil {
+		SetLocal("hook.error", ctx.Error.Error())
+	}
+	if ctx.Request != nil {
+		SetLocal("hook.method", ctx.Request.Method)
+		SetLocal("hook.url", ctx.Request.URL.String())
+	}
+	if ctx.Result != nil {
+		SetLocal("hook.status", strconv.Itoa(ctx.Result.HttpStatus))
+		saved := history
+		history = append(append(make([]Result, 0, len(saved)+1), saved...), *ctx.Result)
+		defer func() { history = saved }()
+	}
+
+	// The options of the command running the hook are restored for the command
+	savedOptions := globalOptions
+	savedError := LastError
+	defer func() {
+		globalOptions = savedOptions
+		LastError = savedError
+	}()
+
+	LastError = 0
+	_, _, err := processScript(strings.NewReader(alias+"\n"), "", false)
+	if err == nil && LastError != 0 {
+		err = NewExitCodeError(errors.New("hook command failed"), LastError)
+	}
+	return err
+}
+
+// HookCommand -- manage the script hooks and change the request of a
+// pre-request hook
+type HookCommand struct {
+}
+
+func NewHookCommand() *HookCommand {
+	return &HookCommand{}
+}
+
+func (cmd *HookCommand) GetSubCommands() []string {
+	var commands = []string{"ADD", "REMOVE", "LIST", "HEADER"}
+	return SortedStringSlice(commands)
+}
+
+func (cmd *HookCommand) AddOptions(set CmdSet) {
+	set.SetParameters("[add|remove event alias] [list] [header name [value]]")
+	set.SetUsage(func() {
+		set.PrintUsage(ConsoleWriter())
+		cmd.ExtendedUsage(ConsoleWriter())
+	})
+	AddCommonCmdOptions(set, CmdDebug)
+}
+
+func (cmd *HookCommand) ExtendedUsage(w io.Writer) {
+	fmt.Fprintf(w, "\nAdditional Information:\n")
+	fmt.Fprintf(w, "\nEvents:\n")
+	for _, event := range hookEvents {
+		fmt.Fprintf(w, "  %s\n", event)
+	}
+	fmt.Fprintf(w, "\nHEADER sets (or removes without a value) a header of the request of a pre-request hook\n")
+}
+
+func (cmd *HookCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return cmd.listHooks()
+	}
+
+	switch args[0] {
+	case "ADD":
+		if len(args) != 3 {
+			return ErrArguments
+		}
+		return RegisterScriptHook(HookEvent(strings.ToLower(args[1])), args[2])
+	case "REMOVE":
+		if len(args) != 3 {
+			return ErrArguments
+		}
+		return RemoveHook(HookEvent(strings.ToLower(args[1])), args[2])
+	case "LIST":
+		return cmd.listHooks()
+	case "HEADER":
+		return cmd.setHeader(args[1:])
+	default:
+		return ErrInvalidSubCommand
+	}
+}
+
+func (cmd *HookCommand) listHooks() error {
+	for _, event := range hookEvents {
+		for _, h := range hookStore[event] {
+			if h.fn != nil {
+				fmt.Fprintf(OutputWriter(), "%s %s (go)\n", event, h.name)
+			} else {
+				fmt.Fprintf(OutputWriter(), "%s %s\n", event, h.name)
+			}
+		}
+	}
+	return nil
+}
+
+func (cmd *HookCommand) setHeader(args []string) error {
+	if runningHook == nil || runningHook.Request == nil {
+		return errors.New("HEADER is only supported in a pre-request hook")
+	}
+	switch len(args) {
+	case 1:
+		runningHook.Request.Header.Del(args[0])
+	case 2:
+		runningHook.Request.Header.Set(args[0], args[1])
+	default:
+		return ErrArguments
+	}
+	return nil
+}
+
+// startConcurrentJobs -- mark the workers of a job processor running until
+// the returned function is called
+func startConcurrentJobs() func() {
+	atomic.AddInt32(&concurrentJobs, 1)
+	return func() { atomic.AddInt32(&concurrentJobs, -1) }
+}
@@ -0,0 +1,190 @@
+package shell
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func addTestHook(t *testing.T, event HookEvent, name string, fn HookFunc) {
+	t.Helper()
+	if err := RegisterHook(event, name, fn); err != nil {
+		t.Fatalf("unable to register hook: %s", err.Error())
+	}
+	t.Cleanup(func() { RemoveHook(event, name) })
+}
+
+func addTestScriptHook(t *testing.T, event HookEvent, alias string, command string) {
+	t.Helper()
+	runTestScript("") // register the record command
+	if err := AddAlias(alias, command, true); err != nil {
+		t.Fatalf("unable to add alias: %s", err.Error())
+	}
+	if err := RegisterScriptHook(event, alias); err != nil {
+		t.Fatalf("unable to register hook: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		RemoveHook(event, alias)
+		RemoveAlias(alias)
+	})
+}
+
+func newEchoServer(t *testing.T, header string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Header.Get(header)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCommandHooksRunAroundCommands(t *testing.T) {
+	commands := make([]string, 0)
+	addTestHook(t, HookPreCommand, "pre", func(ctx *HookContext) error {
+		commands = append(commands, "pre "+ctx.Command)
+		return nil
+	})
+	addTestHook(t, HookPostCommand, "post", func(ctx *HookContext) error {
+		if ctx.Error != nil {
+			commands = append(commands, "post "+ctx.Command+" failed")
+		} else {
+			commands = append(commands, "post "+ctx.Command)
+		}
+		return nil
+	})
+
+	verifyRecorded(t, runTestScript("record a\nrecord fail\n"), "a")
+	verifyRecorded(t, commands, "pre RECORD", "post RECORD", "pre RECORD", "post RECORD failed")
+}
+
+func TestCommandHookErrorFailsCommand(t *testing.T) {
+	addTestHook(t, HookPreCommand, "deny", func(ctx *HookContext) error {
+		if len(ctx.Args) > 0 && ctx.Args[0] == "denied" {
+			return errors.New("not allowed")
+		}
+		return nil
+	})
+
+	verifyRecorded(t, runTestScript("record denied\nrecord allowed\n"), "allowed")
+	if LastError != 0 {
+		t.Errorf("expected the last command to succeed but got %d", LastError)
+	}
+	runTestScript("record denied\n")
+	if LastError != ExitCommandFailed {
+		t.Errorf("expected exit code %d but got %d", ExitCommandFailed, LastError)
+	}
+}
+
+func TestScriptHookRunsAlias(t *testing.T) {
+	addTestScriptHook(t, HookPreCommand, "recordhook", "record hook %%hook.command%%")
+
+	verifyRecorded(t, runTestScript("record x\n"), "hook RECORD x", "x")
+	if GetGlobal("hook.command") != nil {
+		t.Errorf("expected the hook variables to be local")
+	}
+}
+
+func TestRequestHooksChangeHeadersAndSeeResult(t *testing.T) {
+	server := newEchoServer(t, "X-Correlation-Id")
+	addTestScriptHook(t, HookPreRequest, "correlation", "hook header X-Correlation-Id abc")
+	var status int
+	var text string
+	addTestHook(t, HookPostRequest, "status", func(ctx *HookContext) error {
+		status = ctx.Result.HttpStatus
+		text = ctx.Result.Text
+		return nil
+	})
+
+	client := NewRestClient()
+	resp, err := client.DoMethod(http.MethodGet, nil, server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	if resp.Text != "abc" {
+		t.Errorf("expected the hook header to be sent but got %q", resp.Text)
+	}
+
+	if err := RestCompletionHandler(resp, nil, nil); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+	if status != http.StatusOK || text != "abc" {
+		t.Errorf("expected the hook to see the result but got %d %q", status, text)
+	}
+}
+
+func TestPostRequestHookFailureRecordsResult(t *testing.T) {
+	addTestScriptHook(t, HookPostRequest, "checkstatus", "record status %%hook.status%%")
+	addTestHook(t, HookPostRequest, "fails", func(ctx *HookContext) error {
+		return NewExitCodeError(errors.New("server error"), ExitAssertionFailed)
+	})
+
+	testRecorder.lines = nil
+	err := PushResponse(makeRestResponse("boom", "text/plain", 500), nil)
+	verifyRecorded(t, testRecorder.lines, "status 500")
+	if err == nil || GetExitCode(err) != ExitAssertionFailed {
+		t.Errorf("expected the hook error with the exit code of the hook but got %v", err)
+	}
+	if result, _ := PeekResult(0); result.Text != "boom" {
+		t.Errorf("expected the result to be recorded")
+	}
+}
+
+func TestRequestHooksOfConcurrentJobs(t *testing.T) {
+	server := newEchoServer(t, "X-Job")
+	var calls int32
+	addTestHook(t, HookPreRequest, "job", func(ctx *HookContext) error {
+		atomic.AddInt32(&calls, 1)
+		ctx.Request.Header.Set("X-Job", "go")
+		return nil
+	})
+	addTestScriptHook(t, HookPreRequest, "scripthook", "record %%hook.url%%")
+
+	var mutex sync.Mutex
+	texts := make([]string, 0)
+	ProcessJob(JobOptions{
+		Concurrency: 4,
+		Iterations:  40,
+		JobMaker: func() JobFunction {
+			client := NewRestClient()
+			return func() (*RestResponse, error) {
+				return client.DoMethod(http.MethodGet, nil, server.URL)
+			}
+		},
+		CompletionHandler: func(job int, jc JobContext, resp *RestResponse) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			texts = append(texts, resp.Text)
+		},
+	}, nil)
+
+	if calls != 40 || len(texts) != 40 || texts[0] != "go" || texts[39] != "go" {
+		t.Errorf("expected the Go hook for every request: %d calls, %v", calls, texts)
+	}
+	if len(testRecorder.lines) != 0 {
+		t.Errorf("expected no script hooks for concurrent jobs but got %v", testRecorder.lines)
+	}
+
+	// Script hooks run again for the requests of the shell
+	client := NewRestClient()
+	client.DoMethod(http.MethodGet, nil, server.URL)
+	verifyRecorded(t, testRecorder.lines, server.URL)
+}
+
+func TestHookCommand(t *testing.T) {
+	runTestScript("hook add pre-request nosuchalias\n")
+	if LastError == 0 {
+		t.Errorf("expected an error for a missing alias")
+	}
+	runTestScript("hook header X-Test value\n")
+	if LastError == 0 {
+		t.Errorf("expected an error for HEADER outside a pre-request hook")
+	}
+
+	AddAlias("hooktest", "record hooked", true)
+	defer RemoveAlias("hooktest")
+	verifyRecorded(t, runTestScript("hook add post-command hooktest\nrecord x\nhook remove post-command hooktest\nrecord y\n"),
+		"hooked", "x", "hooked", "y")
+}
@@ -22,11 +22,16 @@ func init() {
 	ensureCategory(CategoryUtilities)
 
 	addCommands()
+	addControlBlocks()
 }
 
 func addCommands() {
 	AddCommand("rem", CategoryUtilities, NewRemCommand())
 	AddCommand("run", CategoryUtilities, NewRunCommand())
+	AddCommand("call", CategoryUtilities, NewCallCommand())
+	AddCommand("exit", CategoryUtilities, NewExitCommand())
+	AddCommand("import", CategoryUtilities, NewImportCommand())
+	AddCommand("hook", CategoryUtilities, NewHookCommand())
 	AddCommand("quit", CategoryUtilities, nil)
 }
 
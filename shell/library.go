@@ -0,0 +1,206 @@
+// Script libraries
+//
+// Scripts that are not found relative to the working directory are searched
+// in the directories of the script search path: the .config.restshell.path
+// variable followed by the RESTSHELL_PATH environment variable. Directories
+// are separated by the path list separator (':' or ';' on Windows).
+//
+// IMPORT loads the procedures and aliases of a library script once per
+// session; the other commands of a library are not executed.
+
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Settings of the script search path
+var (
+	ScriptPathVariable    = ".config.restshell.path"
+	ScriptPathEnvironment = "RESTSHELL_PATH"
+)
+
+var importedLibraries = make(map[string]bool)
+
+// GetScriptPath -- the directories of the script search path in search order
+func GetScriptPath() []string {
+	dirs := make([]string, 0)
+	for _, list := range []string{GetGlobalString(ScriptPathVariable), os.Getenv(ScriptPathEnvironment)} {
+		for _, dir := range filepath.SplitList(list) {
+			if dir = strings.TrimSpace(dir); len(dir) > 0 {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// FindScript -- find a script relative to the working directory or in the
+// directories of the search path; returns the file name of the script
+func FindScript(file string) (string, error) {
+	script, err := ValidateScriptExists(file)
+	if err == nil || len(script) > 0 || len(file) == 0 || filepath.IsAbs(file) {
+		return script, err
+	}
+
+	for _, dir := range GetScriptPath() {
+		if script, pathErr := ValidateScriptExists(filepath.Join(dir, file)); pathErr == nil {
+			if IsCmdDebugEnabled() {
+				fmt.Fprintf(ConsoleWriter(), "Found script in search path: %s\n", script)
+			}
+			return script, nil
+		}
+	}
+	return "", err
+}
+
+// listScriptPath -- list the scripts in the directories of the search path
+// with their header comments
+func listScriptPath() error {
+	dirs := GetScriptPath()
+	if len(dirs) == 0 {
+		fmt.Fprintln(OutputWriter(), "The script search path is empty")
+		return nil
+	}
+
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*"+DefaultScriptExtension))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			h, err := os.Open(file)
+			if err != nil {
+				fmt.Fprintf(ErrorWriter(), "Unable to read script: %s\n", file)
+				continue
+			}
+			fmt.Fprintf(OutputWriter(), "%s:\n", file)
+			listfile(h, true)
+			h.Close()
+		}
+	}
+	return nil
+}
+
+// ImportCommand -- load the procedures and aliases of library scripts
+type ImportCommand struct {
+	forceOption *bool
+}
+
+func NewImportCommand() *ImportCommand {
+	return &ImportCommand{}
+}
+
+func (cmd *ImportCommand) AddOptions(set CmdSet) {
+	set.SetParameters("[libraries...]")
+	cmd.forceOption = set.BoolLong("force", 'f', "Load libraries that were already imported")
+	AddCommonCmdOptions(set, CmdDebug, CmdVerbose)
+}
+
+func (cmd *ImportCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		for _, library := range SortedStringSlice(GetImportedLibraries()) {
+			fmt.Fprintln(OutputWriter(), library)
+		}
+		return nil
+	}
+
+	for _, library := range args {
+		if err := ImportLibrary(library, *cmd.forceOption); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetImportedLibraries -- the files of the libraries imported in the session
+func GetImportedLibraries() []string {
+	list := make([]string, 0, len(importedLibraries))
+	for library := range importedLibraries {
+		list = append(list, library)
+	}
+	return list
+}
+
+// ImportLibrary -- load the procedures and aliases of a library script found
+// in the working directory or the search path unless already imported
+func ImportLibrary(library string, force bool) error {
+	file, err := FindScript(library)
+	if err != nil {
+		return newScriptError(fmt.Errorf("library %s: %s", library, err.Error()))
+	}
+	if abspath, err := filepath.Abs(file); err == nil {
+		file = abspath
+	}
+
+	if importedLibraries[file] && !force {
+		if IsCmdVerboseEnabled() {
+			fmt.Fprintf(OutputWriter(), "Library already imported: %s\n", file)
+		}
+		return nil
+	}
+
+	if err := loadLibrary(file); err != nil {
+		return err
+	}
+	importedLibraries[file] = true
+	if IsCmdVerboseEnabled() {
+		fmt.Fprintf(OutputWriter(), "Imported library: %s\n", file)
+	}
+	return nil
+}
+
+// loadLibrary -- define the procedures and execute the ALIAS commands of a
+// library script; other blocks and commands are skipped
+func loadLibrary(file string) error {
+	h, err := os.Open(file)
+	if err != nil {
+		return newScriptError(errors.New("Failed to read library: " + err.Error()))
+	}
+	defer h.Close()
+
+	p := newProcessor("", true)
+	frame, pop := pushCallFrame("import " + file)
+	defer pop()
+	p.frame = frame
+
+	LastError = 0
+	source := newScriptReader(h, file)
+	for !p.isStopped() {
+		line, ok := source.ReadLine()
+		if !ok {
+			break
+		}
+
+		keyword := getLineKeyword(line.Text)
+		if isBlockKeyword(keyword) {
+			block, err := parseBlock(line, source.ReadLine)
+			if err != nil {
+				p.setLine(line)
+				p.reportError(keyword, newScriptError(err))
+			} else if keyword == "PROC" {
+				p.executeBlock(block)
+			}
+		} else if keyword == "ALIAS" {
+			p.executeLine(line)
+		}
+	}
+	if err := source.Err(); err != nil {
+		return newScriptError(errors.New("Failed to read library: " + err.Error()))
+	}
+
+	if p.trapped != nil {
+		return p.trapped
+	}
+	if p.exitErr != nil {
+		return p.exitErr
+	}
+	if LastError != 0 {
+		return NewExitCodeError(fmt.Errorf("errors loading library: %s", file), LastError)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useScriptPath -- set the search path variable for a test
+func useScriptPath(t *testing.T, dirs ...string) {
+	t.Helper()
+	SetGlobal(ScriptPathVariable, strings.Join(dirs, string(os.PathListSeparator)))
+	t.Cleanup(func() { RemoveGlobal(ScriptPathVariable) })
+}
+
+func TestFindScriptSearchesPath(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(second, "lib.rshell"), []byte("rem lib\n"), 0644)
+	t.Setenv(ScriptPathEnvironment, second)
+
+	if _, err := FindScript("lib"); err != nil {
+		t.Errorf("expected the script in the environment path: %s", err.Error())
+	}
+
+	os.WriteFile(filepath.Join(first, "lib.rshell"), []byte("rem lib\n"), 0644)
+	useScriptPath(t, first)
+	if script, err := FindScript("lib"); err != nil || script != filepath.Join(first, "lib.rshell") {
+		t.Errorf("expected the script in the variable path first but got %s (%v)", script, err)
+	}
+
+	if _, err := FindScript("missing"); err == nil {
+		t.Errorf("expected an error for a missing script")
+	}
+	if dirs := GetScriptPath(); len(dirs) != 2 || dirs[0] != first || dirs[1] != second {
+		t.Errorf("unexpected search path: %v", dirs)
+	}
+}
+
+func TestRunFindsScriptInPath(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pathscript.rshell"), []byte("record %%1%%\n"), 0644)
+	useScriptPath(t, dir)
+
+	verifyRecorded(t, runTestScript("run pathscript -- found\n"), "found")
+}
+
+func TestImportLoadsLibraryOnce(t *testing.T) {
+	dir := t.TempDir()
+	library := filepath.Join(dir, "greetings.rshell")
+	os.WriteFile(library, []byte(`REM Greeting procedures
+record loaded
+PROC libgreet name
+  record hello %%name%%
+ENDPROC
+IF TRUE
+  record skipped
+ENDIF
+`), 0644)
+	useScriptPath(t, dir)
+
+	verifyRecorded(t, runTestScript("import greetings\nlibgreet world\n"), "hello world")
+	if LastError != 0 {
+		t.Errorf("expected the import to succeed but got %d", LastError)
+	}
+
+	os.WriteFile(library, []byte("PROC libgreet name\n  record hi %%name%%\nENDPROC\n"), 0644)
+	verifyRecorded(t, runTestScript("import greetings\nlibgreet again\n"), "hello again")
+	verifyRecorded(t, runTestScript("import --force greetings\nlibgreet again\n"), "hi again")
+
+	found := false
+	for _, imported := range GetImportedLibraries() {
+		found = found || imported == library
+	}
+	if !found {
+		t.Errorf("expected %s in the imported libraries %v", library, GetImportedLibraries())
+	}
+}
+
+func TestImportReportsErrors(t *testing.T) {
+	runTestScript("import nosuchlibrary\n")
+	if LastError != ExitScriptError {
+		t.Errorf("expected exit code %d but got %d", ExitScriptError, LastError)
+	}
+
+	file := writeTestScript(t, "broken.rshell", "PROC broken\n")
+	runTestScript("import " + file + "\n")
+	if LastError != ExitScriptError {
+		t.Errorf("expected exit code %d but got %d", ExitScriptError, LastError)
+	}
+}
+
+func TestRunListShowsScriptsInPath(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "auth.rshell"), []byte("REM Login helpers\nrecord x\n"), 0644)
+	useScriptPath(t, dir)
+
+	output := captureTestScript("run --list\n")
+	verifyOutputContains(t, output, filepath.Join(dir, "auth.rshell")+":\nREM Login helpers\n")
+	if strings.Contains(output, "record x") {
+		t.Errorf("expected only the header of the script:\n%s", output)
+	}
+}
+
+func TestLintReadsImportedLibraries(t *testing.T) {
+	files := map[string]string{
+		"main.rshell":      "import lib/procs\nlibcheck value\n",
+		"lib/procs.rshell": "PROC libcheck name\n  record %%name%%\nENDPROC\n",
+	}
+	runTestScript("") // register the record command
+	if problems := lintTestScripts(t, files, "main.rshell"); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
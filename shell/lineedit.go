@@ -0,0 +1,426 @@
+// Interactive line editing
+//
+// The line editor reads the commands of an interactive session from a
+// terminal. The line can be edited with the arrow keys and the common emacs
+// control keys, earlier commands are recalled with the up and down keys and
+// the tab key completes commands, options, variables and file names.
+//
+// The history is saved in a file next to the init script (.rsconfig.history)
+// so commands can be recalled in later sessions. The file is only readable by
+// the user and a line starting with a space is not saved (e.g. a command
+// with a password).
+
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Settings for the history of the interactive session
+var (
+	DefaultHistoryFileExt = ".history"
+	MaxHistoryLines       = 500
+)
+
+// Keys handled by the line editor; control keys are their character values
+// and the keys of escape sequences are negative
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyLineFeed  = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+
+	keyUnknown = -1
+	keyUp      = -2
+	keyDown    = -3
+	keyLeft    = -4
+	keyRight   = -5
+	keyHome    = -6
+	keyEnd     = -7
+	keyDelete  = -8
+)
+
+// completion -- the candidates replacing the text of a line from start to
+// the cursor; the suffix is appended when a single candidate is completed
+type completion struct {
+	start      int
+	candidates []string
+	suffix     string
+}
+
+// lineEditor -- edits lines from a terminal; the editor is a reader returning
+// the edited lines to the command processor
+type lineEditor struct {
+	in          io.Reader
+	out         io.Writer
+	fd          int // Terminal placed in raw mode while editing; -1 for none
+	prompt      string
+	history     []string
+	historyFile string
+	complete    func(text string) completion
+	pending     []byte // Input read but not processed
+	buffer      []byte // Edited lines not read by the processor
+}
+
+// newTerminalLineEditor -- create a line editor for standard input saving the
+// history in the history file
+func newTerminalLineEditor(historyFile string) *lineEditor {
+	e := newLineEditor(os.Stdin, os.Stdout, historyFile)
+	e.fd = int(os.Stdin.Fd())
+	return e
+}
+
+func newLineEditor(in io.Reader, out io.Writer, historyFile string) *lineEditor {
+	return &lineEditor{
+		in:          in,
+		out:         out,
+		fd:          -1,
+		history:     loadHistory(historyFile),
+		historyFile: historyFile,
+		complete:    completeLine,
+	}
+}
+
+// getHistoryFileName -- the history file is next to the init script found in
+// the current directory or the executable directory
+func getHistoryFileName() string {
+	name := DefaultInitFileName + DefaultHistoryFileExt
+	if _, err := os.Stat(DefaultInitFileName); err != nil {
+		return filepath.Join(GetExeDirectory(), name)
+	}
+	return name
+}
+
+// SetPrompt -- set the prompt displayed when the next line is edited
+func (e *lineEditor) SetPrompt(prompt string) {
+	e.prompt = prompt
+}
+
+// Read -- read the edited lines
+func (e *lineEditor) Read(p []byte) (int, error) {
+	if len(e.buffer) == 0 {
+		line, err := e.ReadLine()
+		if err != nil {
+			return 0, err
+		}
+		e.buffer = []byte(line + "\n")
+	}
+	n := copy(p, e.buffer)
+	e.buffer = e.buffer[n:]
+	return n, nil
+}
+
+// ReadLine -- edit a line; Ctrl-C returns an empty line and Ctrl-D on an
+// empty line returns io.EOF
+func (e *lineEditor) ReadLine() (string, error) {
+	if e.fd >= 0 {
+		if state, err := terminal.MakeRaw(e.fd); err == nil {
+			defer terminal.Restore(e.fd, state)
+		}
+	}
+
+	line := []rune{}
+	pos := 0
+	index := len(e.history)
+	saved := ""
+	e.refresh(line, pos)
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			return "", err
+		}
+
+		switch key {
+		case keyEnter, keyLineFeed:
+			io.WriteString(e.out, "\r\n")
+			e.addHistory(string(line))
+			return string(line), nil
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", nil
+		case keyCtrlD:
+			if len(line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case keyDelete:
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case keyBackspace, keyCtrlH:
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case keyLeft, keyCtrlB:
+			if pos > 0 {
+				pos--
+			}
+		case keyRight, keyCtrlF:
+			if pos < len(line) {
+				pos++
+			}
+		case keyHome, keyCtrlA:
+			pos = 0
+		case keyEnd, keyCtrlE:
+			pos = len(line)
+		case keyCtrlK:
+			line = line[:pos]
+		case keyCtrlU:
+			line = line[pos:]
+			pos = 0
+		case keyCtrlW:
+			start := pos
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP:
+			if index > 0 {
+				if index == len(e.history) {
+					saved = string(line)
+				}
+				index--
+				line = []rune(e.history[index])
+				pos = len(line)
+			}
+		case keyDown, keyCtrlN:
+			if index < len(e.history) {
+				index++
+				if index == len(e.history) {
+					line = []rune(saved)
+				} else {
+					line = []rune(e.history[index])
+				}
+				pos = len(line)
+			}
+		case keyTab:
+			line, pos = e.completeLine(line, pos)
+		default:
+			if key >= ' ' {
+				line = append(line[:pos], append([]rune{key}, line[pos:]...)...)
+				pos++
+			}
+		}
+		e.refresh(line, pos)
+	}
+}
+
+// refresh -- redraw the prompt and line and position the cursor
+func (e *lineEditor) refresh(line []rune, pos int) {
+	text := "\r" + e.prompt + string(line) + "\x1b[K"
+	if back := len(line) - pos; back > 0 {
+		text += fmt.Sprintf("\x1b[%dD", back)
+	}
+	io.WriteString(e.out, text)
+}
+
+// completeLine -- complete the word before the cursor with the common prefix
+// of the candidates or list the candidates when nothing can be added
+func (e *lineEditor) completeLine(line []rune, pos int) ([]rune, int) {
+	if e.complete == nil {
+		return line, pos
+	}
+
+	c := e.complete(string(line[:pos]))
+	if len(c.candidates) == 0 {
+		return line, pos
+	}
+
+	replacement := commonPrefix(c.candidates)
+	if len(c.candidates) == 1 && !strings.HasSuffix(replacement, string(filepath.Separator)) {
+		replacement += c.suffix
+	}
+
+	word := string(line[c.start:pos])
+	if replacement == word && len(c.candidates) > 1 {
+		candidates := SortedStringSlice(c.candidates)
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+		return line, pos
+	}
+
+	inserted := []rune(replacement)
+	result := append(append(append([]rune{}, line[:c.start]...), inserted...), line[pos:]...)
+	return result, c.start + len(inserted)
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// readKey -- read a key from the input decoding escape sequences and UTF-8
+func (e *lineEditor) readKey() (rune, error) {
+	if len(e.pending) == 0 {
+		if err := e.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	b := e.pending[0]
+	if b == keyEscape {
+		return e.readEscapeSequence()
+	}
+	if b < utf8.RuneSelf {
+		e.pending = e.pending[1:]
+		return rune(b), nil
+	}
+
+	for !utf8.FullRune(e.pending) {
+		if err := e.fill(); err != nil {
+			return 0, err
+		}
+	}
+	r, size := utf8.DecodeRune(e.pending)
+	e.pending = e.pending[size:]
+	return r, nil
+}
+
+// readEscapeSequence -- decode the cursor keys; other escape sequences and
+// a lone escape key are ignored
+func (e *lineEditor) readEscapeSequence() (rune, error) {
+	if len(e.pending) < 2 || (e.pending[1] != '[' && e.pending[1] != 'O') {
+		e.pending = e.pending[1:]
+		return keyUnknown, nil
+	}
+
+	end := 2
+	for {
+		for end < len(e.pending) && (e.pending[end] < 0x40 || e.pending[end] > 0x7e) {
+			end++
+		}
+		if end < len(e.pending) {
+			break
+		}
+		if err := e.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	sequence := string(e.pending[1 : end+1])
+	e.pending = e.pending[end+1:]
+	switch sequence {
+	case "[A", "OA":
+		return keyUp, nil
+	case "[B", "OB":
+		return keyDown, nil
+	case "[C", "OC":
+		return keyRight, nil
+	case "[D", "OD":
+		return keyLeft, nil
+	case "[H", "OH", "[1~", "[7~":
+		return keyHome, nil
+	case "[F", "OF", "[4~", "[8~":
+		return keyEnd, nil
+	case "[3~":
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+func (e *lineEditor) fill() error {
+	buf := make([]byte, 256)
+	for {
+		n, err := e.in.Read(buf)
+		if n > 0 {
+			e.pending = append(e.pending, buf[:n]...)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// addHistory -- add a line to the history and append it to the history file;
+// a line starting with a space is not added to keep secrets out of the file
+func (e *lineEditor) addHistory(line string) {
+	if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, " ") {
+		return
+	}
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > MaxHistoryLines {
+		e.history = e.history[len(e.history)-MaxHistoryLines:]
+	}
+
+	if len(e.historyFile) == 0 {
+		return
+	}
+	h, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer h.Close()
+	h.Chmod(0600) // A history file created by an older version may be readable by others
+	fmt.Fprintln(h, line)
+}
+
+// loadHistory -- read the last lines of a history file; a file grown to
+// twice the maximum lines is rewritten with the last lines
+func loadHistory(file string) []string {
+	history := make([]string, 0)
+	if len(file) == 0 {
+		return history
+	}
+
+	h, err := os.Open(file)
+	if err != nil {
+		return history
+	}
+	scanner := bufio.NewScanner(h)
+	for scanner.Scan() {
+		history = append(history, scanner.Text())
+	}
+	h.Close()
+
+	if len(history) > MaxHistoryLines {
+		rewrite := len(history) >= 2*MaxHistoryLines
+		history = history[len(history)-MaxHistoryLines:]
+		if rewrite {
+			os.WriteFile(file, []byte(strings.Join(history, "\n")+"\n"), 0600)
+		}
+	}
+	return history
+}
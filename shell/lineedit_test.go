@@ -0,0 +1,190 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func newTestLineEditor(input string, historyFile string) *lineEditor {
+	return newLineEditor(strings.NewReader(input), &bytes.Buffer{}, historyFile)
+}
+
+func readEditedLines(t *testing.T, e *lineEditor) []string {
+	t.Helper()
+	lines := make([]string, 0)
+	for {
+		line, err := e.ReadLine()
+		if err == io.EOF {
+			return lines
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestLineEditorEditing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"get /books\r", "get /books"},
+		{"gt\x1b[De\r", "get"},
+		{"abc\x7f\x7fx\r", "ax"},
+		{"world\x01hello \r", "hello world"},
+		{"hello world\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "hello "},
+		{"one two\x17three\r", "one three"},
+		{"abc\x1b[H\x1b[3~\x1b[F!\r", "bc!"},
+		{"héllo\x7f\x7f\x7flo\r", "hélo"},
+		{"discard\x03kept\r", ""},
+	}
+
+	for _, test := range tests {
+		e := newTestLineEditor(test.input, "")
+		if line, err := e.ReadLine(); err != nil {
+			t.Errorf("%q: unexpected error: %s", test.input, err.Error())
+		} else if line != test.expected {
+			t.Errorf("%q: expected %q but got %q", test.input, test.expected, line)
+		}
+	}
+}
+
+func TestLineEditorEndOfInput(t *testing.T) {
+	e := newTestLineEditor("one\rtwo\r\x04", "")
+	lines := readEditedLines(t, e)
+	if strings.Join(lines, "|") != "one|two" {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".rsconfig.history")
+
+	e := newTestLineEditor("first\rsecond\rsecond\r\r\x1b[A\x1b[A\r\x04", file)
+	lines := readEditedLines(t, e)
+	if strings.Join(lines, "|") != "first|second|second||first" {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+
+	data, _ := os.ReadFile(file)
+	if string(data) != "first\nsecond\nfirst\n" {
+		t.Errorf("unexpected history file: %q", string(data))
+	}
+
+	// The history is recalled in a new session; down returns to the new line
+	e = newTestLineEditor("new\x1b[A\x1b[A\x1b[B\x1b[B\r\x1b[A\x1b[A\x1b[A\r\x04", file)
+	lines = readEditedLines(t, e)
+	if strings.Join(lines, "|") != "new|second" {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+}
+
+func TestLineEditorHistoryKeepsSecrets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".rsconfig.history")
+	os.WriteFile(file, []byte("old\n"), 0644)
+
+	e := newTestLineEditor(" set password=secret\rget /books\r\x04", file)
+	if lines := readEditedLines(t, e); len(lines) != 2 || lines[0] != " set password=secret" {
+		t.Errorf("unexpected lines: %v", lines)
+	}
+	if e.history[len(e.history)-1] != "get /books" || len(e.history) != 2 {
+		t.Errorf("expected the line starting with a space to be left out: %v", e.history)
+	}
+
+	data, _ := os.ReadFile(file)
+	if string(data) != "old\nget /books\n" {
+		t.Errorf("unexpected history file: %q", string(data))
+	}
+	if info, err := os.Stat(file); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected the history file to be readable by the user only: %v", info.Mode())
+	}
+}
+
+func TestLoadHistoryKeepsLastLines(t *testing.T) {
+	saved := MaxHistoryLines
+	MaxHistoryLines = 2
+	defer func() { MaxHistoryLines = saved }()
+
+	file := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(file, []byte("1\n2\n3\n4\n5\n"), 0600)
+	history := loadHistory(file)
+	if strings.Join(history, "|") != "4|5" {
+		t.Errorf("unexpected history: %v", history)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "4\n5\n" {
+		t.Errorf("expected the history file to be rewritten: %q", string(data))
+	}
+}
+
+func TestLineEditorReadsForProcessor(t *testing.T) {
+	e := newTestLineEditor("record one\rrecord t\x7ftwo\r\x04", "")
+	verifyRecorded(t, runTestScript(""))
+	CommandProcessor(">> ", e, false, false)
+	verifyRecorded(t, testRecorder.lines, "one", "two")
+}
+
+func TestCompleteLine(t *testing.T) {
+	runTestScript("") // register the record command
+	SetGlobal("completetest.value", "1")
+	AddAlias("completealias", "record", true)
+	defer RemoveAlias("completealias")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "script.rshell"), []byte(""), 0644)
+	os.Mkdir(filepath.Join(dir, "scripts"), 0755)
+	sep := string(filepath.Separator)
+
+	tests := []struct {
+		text       string
+		start      int
+		candidates []string
+	}{
+		{"reco", 0, []string{"record"}},
+		{"RECO", 0, []string{"RECORD"}},
+		{"@reco", 0, []string{"@record"}},
+		{"completeal", 0, []string{"completealias"}},
+		{"run --tr", 4, []string{"--trace"}},
+		{"completealias --hel", 14, []string{"--help"}},
+		{"record %%completetest.v", 7, []string{"%%completetest.value"}},
+		{"record x=%%completetest.v", 7, []string{"x=%%completetest.value"}},
+		{"run " + dir + sep + "scr", 4, []string{dir + sep + "script.rshell", dir + sep + "scripts" + sep}},
+		{"nosuchcommand --x", 14, []string{}},
+	}
+
+	for _, test := range tests {
+		c := completeLine(test.text)
+		if c.start != test.start {
+			t.Errorf("%s: expected start %d but got %d", test.text, test.start, c.start)
+		}
+		if strings.Join(c.candidates, "|") != strings.Join(test.candidates, "|") {
+			t.Errorf("%s: expected %v but got %v", test.text, test.candidates, c.candidates)
+		}
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	runTestScript("") // register the record command
+	SetGlobal("completetest.value", "1")
+	out := &bytes.Buffer{}
+	e := newLineEditor(strings.NewReader("reco\tx %%completetest.v\t\r"), out, "")
+	if line, _ := e.ReadLine(); line != "record x %%completetest.value%%" {
+		t.Errorf("unexpected completion: %q", line)
+	}
+
+	// Ambiguous completions list the candidates
+	out.Reset()
+	e = newLineEditor(strings.NewReader("run --\t\r"), out, "")
+	if line, _ := e.ReadLine(); line != "run --" {
+		t.Errorf("unexpected completion: %q", line)
+	}
+	if !strings.Contains(out.String(), "--check  ") {
+		t.Errorf("expected the options to be listed: %q", out.String())
+	}
+}
@@ -0,0 +1,430 @@
+// Script validation
+//
+// RUN --check validates a script and the scripts it runs without executing
+// any commands. Lines are parsed without variable substitution and checked
+// against the options of the commands, control blocks are checked for
+// balance and valid clauses, and variable references are checked against
+// the variables set by the scripts or already defined.
+
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// lintProblem -- a problem found in a script
+type lintProblem struct {
+	line    scriptLine
+	message string
+}
+
+func (p lintProblem) String() string {
+	if location := p.line.Location(); len(location) > 0 {
+		return location + ": " + p.message
+	}
+	if p.line.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.line.Line, p.message)
+	}
+	return p.message
+}
+
+// scriptLinter -- state of the validation of a tree of scripts
+type scriptLinter struct {
+	problems   []lintProblem
+	visited    map[string]bool
+	procs      map[string]*procedure
+	variables  map[string]bool
+	commands   []scriptLine // Command lines validated after all scripts are read
+	references []scriptLine // Lines with variable references
+}
+
+var lintReferencePattern = regexp.MustCompile(`%%([^%]*?)%%`)
+
+func newScriptLinter() *scriptLinter {
+	return &scriptLinter{
+		visited:   make(map[string]bool),
+		procs:     make(map[string]*procedure),
+		variables: make(map[string]bool),
+	}
+}
+
+// lintScripts -- validate the scripts (or the script lines with exec) and
+// the scripts they run; returns the problems found
+func lintScripts(scripts []string, exec bool) []lintProblem {
+	// Parsing the options of nested commands replaces the options of the
+	// command running the check
+	savedOptions := globalOptions
+	defer func() { globalOptions = savedOptions }()
+
+	l := newScriptLinter()
+	if exec {
+		l.readScript(strings.NewReader(strings.Join(scripts, "\n")), "")
+	} else {
+		for _, script := range scripts {
+			l.readFile(script, script, scriptLine{})
+		}
+	}
+	l.validate()
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		if l.problems[i].line.File != l.problems[j].line.File {
+			return l.problems[i].line.File < l.problems[j].line.File
+		}
+		return l.problems[i].line.Line < l.problems[j].line.Line
+	})
+	return l.problems
+}
+
+func (l *scriptLinter) addProblem(line scriptLine, format string, args ...interface{}) {
+	l.problems = append(l.problems, lintProblem{line: line, message: fmt.Sprintf(format, args...)})
+}
+
+// readFile -- read a script once; the name and caller (the RUN line) of the
+// script are used to report a script that cannot be read
+func (l *scriptLinter) readFile(file string, name string, caller scriptLine) {
+	script, err := FindScript(file)
+	if err != nil {
+		l.addProblem(caller, "script %s: %s", name, err.Error())
+		return
+	}
+
+	if abspath, err := filepath.Abs(script); err == nil {
+		if l.visited[abspath] {
+			return
+		}
+		l.visited[abspath] = true
+	}
+
+	h, err := os.Open(script)
+	if err != nil {
+		l.addProblem(caller, "script %s: %s", name, err.Error())
+		return
+	}
+	defer h.Close()
+	l.readScript(h, script)
+}
+
+// readScript -- parse the lines and blocks of a script
+func (l *scriptLinter) readScript(reader io.Reader, file string) {
+	source := newScriptReader(reader, file)
+	var last scriptLine
+	eof := false
+	next := func() (scriptLine, bool) {
+		line, ok := source.ReadLine()
+		if ok {
+			last = line
+		} else {
+			eof = true
+		}
+		return line, ok
+	}
+
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+
+		if keyword := getLineKeyword(line.Text); isBlockKeyword(keyword) {
+			block, err := parseBlock(line, next)
+			if err != nil {
+				if eof {
+					l.addProblem(line, "%s", err.Error())
+				} else {
+					l.addProblem(last, "%s", err.Error())
+				}
+				continue
+			}
+			l.readBlock(block, file)
+		} else {
+			l.readLine(line, file)
+		}
+	}
+
+	if err := source.Err(); err != nil {
+		l.addProblem(last, "read error: %s", err.Error())
+	}
+}
+
+func (l *scriptLinter) readStatements(statements []statement, file string) {
+	for _, s := range statements {
+		if s.block != nil {
+			l.readBlock(s.block, file)
+		} else {
+			l.readLine(s.line, file)
+		}
+	}
+}
+
+// readBlock -- validate the clauses of a block and collect the variables it sets
+func (l *scriptLinter) readBlock(block *controlBlock, file string) {
+	header := block.clauses[0]
+	args := l.parseArgs(header.line)
+
+	var err error
+	switch block.keyword {
+	case "IF":
+		err = validateIfBlock(block)
+		for _, clause := range block.clauses {
+			if clause.keyword != "ELSE" {
+				if err := lintCondition(clause.line, l.parseArgs(clause.line)); err != nil {
+					l.addProblem(clause.line, "%s: %s", clause.keyword, err.Error())
+				}
+			}
+		}
+	case "WHILE", "UNTIL":
+		var condition []string
+		if condition, _, err = parseLoopCondition(block.keyword, args); err == nil {
+			err = lintCondition(header.line, condition)
+		}
+	case "FOREACH":
+		var loop *foreachArgs
+		if loop, err = parseForeachArgs(args); err == nil {
+			l.variables[loop.name] = true
+			l.variables[loop.index] = true
+		}
+	case "PROC":
+		var proc *procedure
+		if proc, err = newProcedure(args); err == nil {
+			l.procs[proc.name] = proc
+			for _, param := range proc.params {
+				l.variables[param.name] = true
+			}
+		}
+	case "TRY":
+		_, _, err = getTryClauses(block)
+	case "PARALLEL":
+		_, err = parseParallelOptions(args)
+		l.variables["branch"] = true
+		for _, clause := range block.clauses[1:] {
+			if len(l.parseArgs(clause.line)) > 1 {
+				l.addProblem(clause.line, "BRANCH accepts only a name")
+			}
+		}
+	}
+	if err != nil {
+		l.addProblem(header.line, "%s: %s", block.keyword, err.Error())
+	}
+
+	for _, clause := range block.clauses {
+		l.references = append(l.references, clause.line)
+		l.readStatements(clause.body, file)
+	}
+}
+
+// readLine -- collect a command line for validation and read nested scripts
+func (l *scriptLinter) readLine(line scriptLine, file string) {
+	cmdLine, err := parseLintLine(line.Text)
+	if err != nil {
+		l.addProblem(line, "%s", err.Error())
+		return
+	}
+	if cmdLine.IsComment || len(cmdLine.Command) == 0 || cmdLine.Command == "REM" {
+		return
+	}
+
+	if isBlockTerminator(cmdLine.Command) {
+		l.addProblem(line, "no matching block for %s", cmdLine.Command)
+		return
+	}
+
+	l.references = append(l.references, line)
+	switch cmdLine.Command {
+	case "QUIT", "Q", "SHELL", "RETURN":
+		return
+	case "SET":
+		for _, arg := range cmdLine.GetTokens()[1:] {
+			if k, _, ok := strings.Cut(arg, "="); ok && !strings.HasPrefix(arg, "-") {
+				l.variables[k] = true
+			}
+		}
+	case "CALL":
+		if args, err := parseLintOptions(NewCallCommand(), cmdLine.GetTokens()); err == nil {
+			l.variables[args.result] = true
+		}
+	case "RUN":
+		l.readNestedScripts(line, cmdLine, file)
+	case "IMPORT":
+		for _, library := range l.parseArgs(line) {
+			if !strings.HasPrefix(library, "-") && !strings.Contains(library, "%%") {
+				l.readFile(resolveLintScript(library, file), library, line)
+			}
+		}
+	}
+	l.commands = append(l.commands, line)
+}
+
+// readNestedScripts -- read the scripts executed by a RUN line relative to
+// the directory of the script containing the line
+func (l *scriptLinter) readNestedScripts(line scriptLine, cmdLine *Line, file string) {
+	args, err := parseLintOptions(NewRunCommand(), cmdLine.GetTokens())
+	if err != nil || args.exec {
+		return
+	}
+
+	scripts, scriptArgs := splitScriptArguments(args.args)
+	for _, arg := range scriptArgs {
+		if k, _, ok := strings.Cut(arg, "="); ok && IsValidKey(k) {
+			l.variables[k] = true
+		}
+	}
+
+	for _, script := range scripts {
+		if strings.Contains(script, "%%") {
+			continue
+		}
+		l.readFile(resolveLintScript(script, file), script, line)
+	}
+}
+
+// resolveLintScript -- a script is relative to the directory of the script
+// running it or is found in the search path
+func resolveLintScript(script string, file string) string {
+	if filepath.IsAbs(script) || len(file) == 0 {
+		return script
+	}
+	path := filepath.Join(filepath.Dir(file), script)
+	if _, err := ValidateScriptExists(path); err != nil {
+		return script
+	}
+	return path
+}
+
+// validate -- validate the commands and variable references after all the
+// scripts are read so procedures and variables can be defined in any script
+func (l *scriptLinter) validate() {
+	for _, line := range l.commands {
+		cmdLine, _ := parseLintLine(line.Text)
+		if strings.Contains(cmdLine.Command, "%%") {
+			continue
+		}
+
+		tokens := cmdLine.GetTokens()
+		if proc, ok := l.procs[cmdLine.Command]; ok {
+			if _, err := proc.bindArguments(tokens[1:]); err != nil {
+				l.addProblem(line, "%s: %s", cmdLine.Command, err.Error())
+			}
+			continue
+		}
+
+		if err := validateCmd(lintText(line.Text)); err != nil {
+			l.addProblem(line, "%s: %s", cmdLine.Command, err.Error())
+			continue
+		}
+
+		if cmdLine.Command == "CALL" {
+			args, _ := parseLintOptions(NewCallCommand(), tokens)
+			if len(args.args) > 0 && !strings.Contains(args.args[0], "%%") {
+				name := strings.ToUpper(args.args[0])
+				if proc, ok := l.procs[name]; ok {
+					if _, err := proc.bindArguments(args.args[1:]); err != nil {
+						l.addProblem(line, "CALL: %s", err.Error())
+					}
+				} else if !IsProcedure(name) {
+					l.addProblem(line, "CALL: procedure not found: %s", args.args[0])
+				}
+			}
+		}
+	}
+
+	for _, line := range l.references {
+		l.validateReferences(line)
+	}
+}
+
+// validateReferences -- validate the substitution functions and variables
+// referenced by a line
+func (l *scriptLinter) validateReferences(line scriptLine) {
+	for _, match := range lintReferencePattern.FindAllStringSubmatch(line.Text, -1) {
+		name := strings.TrimSpace(match[1])
+		if strings.HasPrefix(name, "=") {
+			if _, err := getExpressionLanguage().NewEvaluable(name[1:]); err != nil {
+				l.addProblem(line, "invalid expression: %s", err.Error())
+			}
+		} else if i := strings.Index(name, "("); i > 0 {
+			if _, ok := GetSubstitutionFunction(name[:i]); !ok {
+				l.addProblem(line, "unknown substitution function: %s", name[:i])
+			}
+		} else if IsValidKey(name) && !l.isVariableSet(name) {
+			l.addProblem(line, "variable is never set: %s", name)
+		}
+	}
+}
+
+func (l *scriptLinter) isVariableSet(name string) bool {
+	if l.variables[name] || GetGlobal(name) != nil || isPositionalKey(name) {
+		return true
+	}
+	switch name {
+	case "argc", DefaultProcResultVariable, ErrorMessageVariable, ErrorCommandVariable, ErrorLocationVariable:
+		return true
+	}
+	return false
+}
+
+// lintText -- the text of a script line with variable substitution disabled
+func lintText(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 || strings.HasPrefix(text, "#") {
+		return text
+	}
+	return "$" + text
+}
+
+func parseLintLine(text string) (*Line, error) {
+	return NewCommandLine(lintText(text), "")
+}
+
+// lintCondition -- validate a condition; the value of an expression or a
+// single variable is only known when the script runs
+func lintCondition(line scriptLine, args []string) error {
+	if strings.Contains(line.Text, "%%=") {
+		return nil
+	}
+	_, err := parseCondition(args, len(args) == 1 && strings.Contains(args[0], "%%"))
+	return err
+}
+
+// parseArgs -- the arguments following the keyword of a block clause
+func (l *scriptLinter) parseArgs(line scriptLine) []string {
+	cmdLine, err := parseLintLine(line.Text)
+	if err != nil {
+		return []string{}
+	}
+	return cmdLine.GetTokens()[1:]
+}
+
+// lintOptions -- the options of RUN and CALL lines used by the linter
+type lintOptions struct {
+	args   []string
+	exec   bool
+	result string
+}
+
+// parseLintOptions -- parse the options of a RUN or CALL line
+func parseLintOptions(cmd Command, tokens []string) (lintOptions, error) {
+	set := NewCmdSet()
+	InitializeCommonCmdOptions(set, CmdHelp)
+	cmd.AddOptions(set)
+	set.Reset()
+	if err := CmdParse(set, tokens); err != nil {
+		return lintOptions{}, err
+	}
+
+	options := lintOptions{args: set.Args()}
+	switch c := cmd.(type) {
+	case *RunCommand:
+		options.exec = *c.execOption
+	case *CallCommand:
+		options.result = *c.resultOption
+		if len(options.args) > 0 && options.args[0] == "--" {
+			options.args = options.args[1:]
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,122 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lintTestScripts(t *testing.T, files map[string]string, script string) []string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	problems := make([]string, 0)
+	for _, problem := range lintScripts([]string{filepath.Join(dir, script)}, false) {
+		problems = append(problems, strings.TrimPrefix(problem.String(), dir+string(filepath.Separator)))
+	}
+	return problems
+}
+
+func TestLintValidScriptTree(t *testing.T) {
+	files := map[string]string{
+		"main.rshell": `# valid script
+PROC greet name greeting=hello
+  record %%greeting%% %%name%%
+ENDPROC
+greet world
+CALL --result out greet name=you
+IF EQ --var host localhost
+  record %%out%%
+ELSE
+  record other
+ENDIF
+FOREACH item --var host
+  record %%item%% %%item.index%%
+ENDFOREACH
+PARALLEL --buffer
+  record one
+BRANCH second
+  record %%branch%%
+END
+run lib/inner -- value=1
+`,
+		"lib/inner.rshell": "record %%value%% %%1%% %%argc%%\n",
+	}
+	SetGlobal("host", "localhost")
+	runTestScript("") // register the record command
+	if problems := lintTestScripts(t, files, "main.rshell"); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestLintReportsProblems(t *testing.T) {
+	files := map[string]string{
+		"main.rshell": `PROC greet name
+ENDPROC
+greet
+nosuchcommand x
+record --bogus
+IF BAD x
+ENDIF
+ENDWHILE
+record %%neverset%% %%nosuchfunc(x)%% %%= 1 + %%
+run inner
+CALL missing
+WHILE EQ x 1
+`,
+		"inner.rshell": "run missing\n",
+	}
+
+	expected := []string{
+		"inner.rshell:1: script missing: file does not exist",
+		"main.rshell:3: GREET: missing argument for parameter: name",
+		"main.rshell:4: NOSUCHCOMMAND: Invalid Command 'NOSUCHCOMMAND'. Try 'help'",
+		"main.rshell:5: RECORD: invalid arguments: unknown option: --bogus",
+		"main.rshell:6: IF: invalid condition operator: BAD",
+		"main.rshell:8: no matching block for ENDWHILE",
+		"main.rshell:9: variable is never set: neverset",
+		"main.rshell:9: unknown substitution function: nosuchfunc",
+		"main.rshell:9: invalid expression: parsing error:  1 +\t:1:5 - 1:5 unexpected EOF while scanning extensions",
+		"main.rshell:11: CALL: procedure not found: missing",
+		"main.rshell:12: WHILE block is missing ENDWHILE",
+	}
+
+	runTestScript("") // register the record command
+	problems := lintTestScripts(t, files, "main.rshell")
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected problems:\n%s", strings.Join(problems, "\n"))
+	}
+}
+
+func TestRunCheckDoesNotExecute(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "check.rshell")
+	os.WriteFile(script, []byte("record executed\n"), 0644)
+
+	recorded := runTestScript("run --check " + script + "\n")
+	verifyRecorded(t, recorded)
+	if LastError != 0 {
+		t.Errorf("expected a valid script to pass the check")
+	}
+
+	os.WriteFile(script, []byte("record executed\nbogus\n"), 0644)
+	runTestScript("run --check " + script + "\n")
+	if LastError == 0 {
+		t.Errorf("expected the check to fail")
+	}
+}
+
+func TestRunCheckKeepsSilentOption(t *testing.T) {
+	script := writeTestScript(t, "silent.rshell", "run --exec \"record nested\"\nrecord done\n")
+
+	output := captureTestScript("run --check -s " + script + "\n")
+	if strings.Contains(output, "No problems found") {
+		t.Errorf("expected no output with the silent option:\n%s", output)
+	}
+	verifyOutputContains(t, captureTestScript("run --check "+script+"\n"), "No problems found")
+}
@@ -0,0 +1,234 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// executeForeachBlock -- execute the body of a FOREACH block for each element
+// of a history path or a delimited variable
+//
+//	FOREACH name [--index var] [--var] [--sep sep] [path options] source
+//
+// The element is stored in the variable "name" and its zero based index in
+// the variable "name.index" unless another index variable is given; both are
+// local to the loop.
+func executeForeachBlock(p *processor, block *controlBlock) error {
+	args, err := p.prepareClause(block.clauses[0])
+	if err != nil {
+		return err
+	}
+
+	loop, err := parseForeachArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var items []string
+	if loop.isVar {
+		items, err = getForeachVariableItems(loop.source, loop.sep)
+	} else {
+		items, err = getForeachHistoryItems(loop.source, loop.historyOptions)
+	}
+	if err != nil {
+		return err
+	}
+
+	defer PushScope("foreach:" + loop.name)()
+	body := block.clauses[0].body
+	for i, item := range items {
+		if p.isStopped() {
+			break
+		}
+		SetLocal(loop.name, item)
+		SetLocal(loop.index, strconv.Itoa(i))
+		p.executeStatements(body)
+	}
+	return nil
+}
+
+// foreachArgs -- the parsed arguments of a FOREACH block
+type foreachArgs struct {
+	name           string
+	index          string
+	isVar          bool
+	sep            string
+	source         string
+	historyOptions HistoryOptions
+}
+
+func parseForeachArgs(args []string) (*foreachArgs, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return nil, errors.New("FOREACH requires a loop variable name")
+	}
+	name := args[0]
+	if !IsValidKey(name) {
+		return nil, ErrInvalidKey
+	}
+
+	set := NewCmdSet()
+	indexOption := set.StringLong("index", 'i', name+".index", "Variable to receive the index of the element", "var")
+	varOption := set.BoolLong("var", 0, "Use the source as a variable containing a delimited list")
+	sepOption := set.StringLong("sep", 0, ",", "Separator of elements in a variable list", "sep")
+	historyOptions := AddHistoryOptions(set, AlternatePaths)
+	set.Reset()
+	if err := CmdParse(set, makeSubTokenArray("FOREACH", args[1:])); err != nil {
+		return nil, err
+	}
+
+	if len(set.Args()) != 1 {
+		return nil, errors.New("FOREACH requires a single path or variable to iterate")
+	}
+	if !IsValidKey(*indexOption) {
+		return nil, ErrInvalidKey
+	}
+
+	return &foreachArgs{
+		name:           name,
+		index:          *indexOption,
+		isVar:          *varOption,
+		sep:            *sepOption,
+		source:         set.Arg(0),
+		historyOptions: historyOptions,
+	}, nil
+}
+
+// getForeachVariableItems -- split the value of a variable into its elements
+func getForeachVariableItems(key string, sep string) ([]string, error) {
+	value := GetGlobal(key)
+	if value == nil {
+		return nil, fmt.Errorf("variable not found: %s", key)
+	}
+
+	str, err := convertLoopValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(str)) == 0 {
+		return []string{}, nil
+	}
+
+	items := strings.Split(str, sep)
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items, nil
+}
+
+// getForeachHistoryItems -- get the elements of a node in the last result;
+// a node that is not a list is a single element
+func getForeachHistoryItems(path string, historyOptions HistoryOptions) ([]string, error) {
+	node, err := historyOptions.GetNodeFromHistory(0, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []string
+	switch t := node.(type) {
+	case []string:
+		items = t
+	case []interface{}:
+		for _, v := range t {
+			str, err := convertLoopValue(v)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, str)
+		}
+	default:
+		str, err := convertLoopValue(t)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, str)
+	}
+	return items, nil
+}
+
+// convertLoopValue -- convert a node to a string for a loop variable; objects
+// and arrays are converted to JSON
+func convertLoopValue(node interface{}) (string, error) {
+	if node == nil {
+		return "", nil
+	}
+	if b, ok := node.(bool); ok {
+		return strconv.FormatBool(b), nil
+	}
+	if str, err := ConvertNodeValueToString(node); err == nil {
+		return str, nil
+	}
+
+	data, err := json.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeWhileBlock -- execute the body of a WHILE block while the condition
+// is met; the condition is tested before each iteration
+//
+//	WHILE [--max n] condition
+func executeWhileBlock(p *processor, block *controlBlock) error {
+	return executeConditionalLoop(p, block, true)
+}
+
+// executeUntilBlock -- execute the body of an UNTIL block until the condition
+// is met; the condition is tested after each iteration
+//
+//	UNTIL [--max n] condition
+func executeUntilBlock(p *processor, block *controlBlock) error {
+	return executeConditionalLoop(p, block, false)
+}
+
+func executeConditionalLoop(p *processor, block *controlBlock, testFirst bool) error {
+	clause := block.clauses[0]
+	for iteration := 0; !p.isStopped(); iteration++ {
+		condition, max, err := p.prepareLoopCondition(clause)
+		if err != nil {
+			return err
+		}
+
+		if testFirst || iteration > 0 {
+			met, err := evaluateCondition(condition, isExpressionClause(clause))
+			if err != nil {
+				return err
+			}
+			if met != testFirst {
+				return nil
+			}
+		}
+
+		if max > 0 && iteration >= max {
+			return fmt.Errorf("%s loop stopped after %d iterations", block.keyword, max)
+		}
+		p.executeStatements(clause.body)
+	}
+	return nil
+}
+
+// prepareLoopCondition -- parse the loop options from the clause returning
+// the condition and the maximum number of iterations (0 is unlimited)
+func (p *processor) prepareLoopCondition(clause blockClause) ([]string, int, error) {
+	args, err := p.prepareClause(clause)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseLoopCondition(clause.keyword, args)
+}
+
+func parseLoopCondition(keyword string, args []string) ([]string, int, error) {
+	set := NewCmdSet()
+	maxOption := set.IntLong("max", 0, 0, "Maximum number of iterations", "n")
+	set.Reset()
+	if err := CmdParse(set, makeSubTokenArray(keyword, args)); err != nil {
+		return nil, 0, err
+	}
+	if *maxOption < 0 {
+		return nil, 0, errors.New("invalid --max value")
+	}
+	return set.Args(), *maxOption, nil
+}
@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestForeachOverHistoryArray(t *testing.T) {
+	PushResponse(makeRestResponse(json1, "application/json", 200), nil)
+	script := `
+FOREACH item car.strarray
+  record %%item.index%%=%%item%%
+ENDFOREACH
+`
+	verifyRecorded(t, runTestScript(script), "0=s1", "1=s2", "2=s2")
+}
+
+func TestForeachOverHistoryObjects(t *testing.T) {
+	PushResponse(makeRestResponse(json1, "application/json", 200), nil)
+	script := `
+FOREACH obj --index i dataarray
+  record %%i%%=%%obj%%
+ENDFOREACH
+`
+	// The objects are JSON; the command line removes the quotes
+	verifyRecorded(t, runTestScript(script), "0={d1:1}", "1={d2:2}")
+}
+
+func TestForeachVariablesAreLocal(t *testing.T) {
+	SetGlobal("looptest", "a,b")
+	SetGlobal("v", "caller")
+	defer RemoveGlobal("v")
+	RemoveGlobal("v.index")
+	script := `
+FOREACH v --var looptest
+  record %%v%%
+ENDFOREACH
+record %%v%% %%v.index%% %%record.count%%
+`
+	verifyRecorded(t, runTestScript(script), "a", "b", "caller %%v.index%% 2")
+}
+
+func TestForeachOverVariableList(t *testing.T) {
+	SetGlobal("looptest", "a, b,c")
+	script := `
+foreach v --var looptest
+  record %%v%%
+endforeach
+`
+	verifyRecorded(t, runTestScript(script), "a", "b", "c")
+
+	SetGlobal("looptest", "a|b")
+	script = `
+FOREACH v --var --sep | looptest
+  record %%v%%
+ENDFOREACH
+`
+	verifyRecorded(t, runTestScript(script), "a", "b")
+}
+
+func TestForeachNestedWithIf(t *testing.T) {
+	SetGlobal("looptest", "1,2,3")
+	script := `
+FOREACH outer --var looptest
+  IF GT --var outer 1
+    FOREACH inner --var looptest
+      IF EQ --var inner %%outer%%
+        record %%outer%%
+      ENDIF
+    ENDFOREACH
+  ENDIF
+ENDFOREACH
+`
+	verifyRecorded(t, runTestScript(script), "2", "3")
+}
+
+func TestForeachEmptyList(t *testing.T) {
+	SetGlobal("looptest", "")
+	script := `
+FOREACH v --var looptest
+  record %%v%%
+ENDFOREACH
+`
+	verifyRecorded(t, runTestScript(script))
+	if LastError != 0 {
+		t.Errorf("unexpected error for an empty list")
+	}
+}
+
+func TestForeachMissingSourceIsAnError(t *testing.T) {
+	script := `
+FOREACH v --var looptest.missing
+  record %%v%%
+ENDFOREACH
+`
+	verifyRecorded(t, runTestScript(script))
+	if LastError == 0 {
+		t.Errorf("expected an error for a missing variable")
+	}
+}
+
+func TestWhileLoop(t *testing.T) {
+	script := `
+WHILE LT --var record.count 3
+  record %%record.count%%
+ENDWHILE
+`
+	verifyRecorded(t, runTestScript(script), "0", "1", "2")
+}
+
+func TestWhileLoopConditionNotMet(t *testing.T) {
+	script := `
+WHILE LASTERR
+  record never
+ENDWHILE
+`
+	verifyRecorded(t, runTestScript(script))
+}
+
+func TestUntilLoopExecutesAtLeastOnce(t *testing.T) {
+	script := `
+UNTIL GTE --var record.count 1
+  record once
+ENDUNTIL
+UNTIL EQ --var record.count 3
+  record more
+ENDUNTIL
+`
+	verifyRecorded(t, runTestScript(script), "once", "more", "more")
+}
+
+func TestLoopMaxIterations(t *testing.T) {
+	script := `
+WHILE --max 2 NEX --var record.missing
+  record loop
+ENDWHILE
+`
+	verifyRecorded(t, runTestScript(script), "loop", "loop")
+	if LastError == 0 {
+		t.Errorf("expected an error when the loop exceeds --max")
+	}
+}
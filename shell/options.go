@@ -41,6 +41,9 @@ const (
 	OptionDefaultBasicAuth            = "[user][,pwd]"
 	OptionDefaultQueryParamAuth       = "[[name,value]...]"
 	OptionDefaultOutputFile           = ""
+	OptionDefaultProxy                = ""
+	OptionDefaultNoProxy              = ""
+	OptionDefaultRetry                = -1 // Use the retry variable
 )
 
 // Structure for all common option values
@@ -69,6 +72,18 @@ type StandardOptions struct {
 	warmingOption        *bool // BM warming iterations (# = concurrency)
 	headersOption        *string
 	headerOption         *StringList
+	proxyOption          *string
+	noProxyOption        *string
+	clientCertOption     *string
+	clientKeyOption      *string
+	clientPasswordOption *string
+	caBundleOption       *string
+	tlsMinOption         *string
+	sniOption            *string
+	retryOption          *int
+	retryOnOption        *string
+	retryDelayOption     *string
+	noJarOption          *bool
 	shortOutputOption    *bool
 	bodyOutputOption     *bool
 	headerOutputOption   *bool
@@ -253,6 +268,42 @@ func AddCommonCmdOptions(set CmdSet, options ...int) {
 			if globalOptions.headerOption == nil {
 				globalOptions.headerOption = set.StringListLong("header", 0, "Set a header [k=v]")
 			}
+			if globalOptions.proxyOption == nil {
+				globalOptions.proxyOption = set.StringLong("proxy", 0, OptionDefaultProxy, "Send requests through a proxy (http, https, socks5 or direct)", "url")
+			}
+			if globalOptions.noProxyOption == nil {
+				globalOptions.noProxyOption = set.StringLong("no-proxy", 0, OptionDefaultNoProxy, "Hosts not sent through the proxy [h1,h2]", "hosts")
+			}
+			if globalOptions.clientCertOption == nil {
+				globalOptions.clientCertOption = set.StringLong("client-cert", 0, "", "Client certificate (PEM or PKCS#12 .p12/.pfx)", "file")
+			}
+			if globalOptions.clientKeyOption == nil {
+				globalOptions.clientKeyOption = set.StringLong("client-key", 0, "", "Private key of a PEM client certificate", "file")
+			}
+			if globalOptions.clientPasswordOption == nil {
+				globalOptions.clientPasswordOption = set.StringLong("client-password", 0, "", "Password of a PKCS#12 client certificate", "password")
+			}
+			if globalOptions.caBundleOption == nil {
+				globalOptions.caBundleOption = set.StringLong("ca-bundle", 0, "", "Trust only the CA certificates of a PEM file", "file")
+			}
+			if globalOptions.tlsMinOption == nil {
+				globalOptions.tlsMinOption = set.StringLong("tls-min", 0, "", "Minimum TLS version (1.0, 1.1, 1.2 or 1.3)", "version")
+			}
+			if globalOptions.sniOption == nil {
+				globalOptions.sniOption = set.StringLong("sni", 0, "", "Server name sent and verified in the TLS handshake", "name")
+			}
+			if globalOptions.retryOption == nil {
+				globalOptions.retryOption = set.IntLong("retry", 0, OptionDefaultRetry, "Retry transient failures a number of times", "count")
+			}
+			if globalOptions.retryOnOption == nil {
+				globalOptions.retryOnOption = set.StringLong("retry-on", 0, "", "Status codes and network errors to retry ["+DefaultRetryOn+"]", "list")
+			}
+			if globalOptions.retryDelayOption == nil {
+				globalOptions.retryDelayOption = set.StringLong("retry-delay", 0, "", "Delay of the first retry [500ms]", "duration")
+			}
+			if globalOptions.noJarOption == nil {
+				globalOptions.noJarOption = set.BoolLong("no-jar", 0, "Do not send or store the cookies of the session cookie jar")
+			}
 		case CmdFormatOutput:
 			if globalOptions.shortOutputOption == nil {
 				globalOptions.shortOutputOption = set.BoolLong("out-short", 0, "Output the short response (overrides verbose)")
@@ -346,6 +397,14 @@ func GetCmdHeaderValues() []string {
 	return globalOptions.GetHeaderValues()
 }
 
+func GetCmdProxyValue() string {
+	return globalOptions.GetProxyValue()
+}
+
+func GetCmdNoProxyValue() string {
+	return globalOptions.GetNoProxyValue()
+}
+
 func GetCmdIterationValue() int {
 	return globalOptions.GetCmdIterationValue()
 }
@@ -366,6 +425,10 @@ func IsCmdReconnectEnabled() bool {
 	return globalOptions.IsReconnectEnabled()
 }
 
+func IsCmdCookieJarEnabled() bool {
+	return globalOptions.IsCookieJarEnabled()
+}
+
 func IsCmdWarmingEnabled() bool {
 	return globalOptions.IsWarmingEnabled()
 }
@@ -492,6 +555,22 @@ func (o *StandardOptions) GetHeaderValues() []string {
 	return result
 }
 
+// GetProxyValue -- the proxy of the option or the proxy variable
+func (o *StandardOptions) GetProxyValue() string {
+	if o.proxyOption != nil && *o.proxyOption != OptionDefaultProxy {
+		return strings.TrimSpace(*o.proxyOption)
+	}
+	return strings.TrimSpace(GetGlobalString(ProxyVariable))
+}
+
+// GetNoProxyValue -- the hosts of the option or the no-proxy variable
+func (o *StandardOptions) GetNoProxyValue() string {
+	if o.noProxyOption != nil && *o.noProxyOption != OptionDefaultNoProxy {
+		return *o.noProxyOption
+	}
+	return GetGlobalString(NoProxyVariable)
+}
+
 // GetBasicAuthContext -- get the Auth context for the basic auth parameters specified
 func (o *StandardOptions) GetBasicAuthContext(fallback Auth) Auth {
 	if o.basicAuthOption != nil && *o.basicAuthOption != OptionDefaultBasicAuth {
@@ -586,6 +665,11 @@ func (o *StandardOptions) IsReconnectEnabled() bool {
 	return o.reconnectOption != nil && *o.reconnectOption
 }
 
+// IsCookieJarEnabled -- true unless the --no-jar option is set
+func (o *StandardOptions) IsCookieJarEnabled() bool {
+	return o.noJarOption == nil || !*o.noJarOption
+}
+
 func (o *StandardOptions) IsWarmingEnabled() bool {
 	return o.warmingOption != nil && *o.warmingOption
 }
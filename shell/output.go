@@ -15,16 +15,42 @@ func RestCompletionHandler(response *RestResponse, resperr error, shortDisplay S
 
 	if resperr != nil {
 		PushError(resperr)
-		return errors.New("Network Error: " + resperr.Error())
+		return NewExitCodeError(errors.New("Network Error: "+resperr.Error()), ExitNetworkError)
 	}
 
-	PushResponse(response, resperr)
+	hookErr := PushResponse(response, resperr)
 	result, err := PeekResult(0)
 	if err != nil {
 		return errors.New("Error: Unable to get the result")
 	}
 
-	return OutputResult(result, shortDisplay)
+	if err := OutputResult(result, shortDisplay); err != nil {
+		return err
+	}
+	return hookErr
+}
+
+// DownloadCompletionHandler -- Helper function to push the result of a
+// download and perform output processing; a response that was not saved is
+// processed like other responses and fails the download
+func DownloadCompletionHandler(response *RestResponse, info *ContentInfo, resperr error) error {
+	if resperr != nil || info == nil {
+		if err := RestCompletionHandler(response, resperr, nil); err != nil {
+			return err
+		}
+		return fmt.Errorf("download failed: %s", response.GetStatusString())
+	}
+
+	hookErr := PushDownload(response, *info, nil)
+	result, err := PeekResult(0)
+	if err != nil {
+		return errors.New("Error: Unable to get the result")
+	}
+
+	if err := OutputResult(result, nil); err != nil {
+		return err
+	}
+	return hookErr
 }
 
 // JsonCompletionHandler -- Helper function to push json result data and perform output processing
@@ -48,7 +74,7 @@ func MessageCompletionHandler(str string, resperr error) error {
 
 	if resperr != nil {
 		PushError(resperr)
-		return errors.New("Network Error: " + resperr.Error())
+		return NewExitCodeError(errors.New("Network Error: "+resperr.Error()), ExitNetworkError)
 	}
 
 	PushText("text/plain", str, resperr)
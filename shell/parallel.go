@@ -0,0 +1,574 @@
+// Parallel execution
+//
+// Scripts (RUN --parallel) and the branches of a PARALLEL block are executed
+// at the same time. Each branch runs in a child shell started with a copy of
+// the variables, aliases, procedures, history, cookie jar, auth contexts and
+// alias hooks of the shell, so a branch cannot change the state of the shell
+// or of the other branches. The client settings kept in variables (e.g. TLS,
+// proxy and retry) apply to the branches as well; hooks registered from Go
+// are registered again by the child shell itself.
+//
+//	PARALLEL [--fail-fast] [--buffer]
+//	  command                 # each statement is a branch
+//	BRANCH [name]
+//	  ...                     # the statements of the clause are a branch
+//	END                       # or ENDPARALLEL
+//
+// The output lines of a branch are prefixed with the branch name or, when
+// buffered, displayed together when the branch completes. By default all the
+// branches complete; with --fail-fast the remaining branches are stopped
+// when a branch fails.
+
+package shell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParallelBranchVariable -- environment variable with the state file of a
+// branch executed by a child shell
+var ParallelBranchVariable = "RESTSHELL_PARALLEL_BRANCH"
+
+// parallelOptions -- the failure and output policy of parallel branches
+type parallelOptions struct {
+	failFast bool
+	buffer   bool
+}
+
+// parallelBranch -- a script executed by a child shell
+type parallelBranch struct {
+	name   string
+	script string
+}
+
+// branchResult -- the completion of a branch
+type branchResult struct {
+	code     int
+	canceled bool
+	err      error
+}
+
+// branchState -- the state of the shell copied to a branch
+type branchState struct {
+	Name       string
+	Script     string
+	Variables  map[string]string
+	Aliases    map[string]string
+	Procedures []procedureState
+	History    []resultState
+	Jar        []JarCookie
+	Auth       map[string]requestAuth
+	Hooks      map[HookEvent][]string // Aliases of the script hooks
+}
+
+// procedureState -- a procedure definition with the body as script text
+type procedureState struct {
+	Name   string
+	Params []procParamState
+	Body   string
+}
+
+type procParamState struct {
+	Name     string
+	Value    string
+	Optional bool
+}
+
+// resultState -- a history result without the parsed maps
+type resultState struct {
+	Text             string
+	Error            string
+	HttpStatus       int
+	HttpStatusString string
+	Attempts         int `json:",omitempty"`
+	ContentType      string
+	Headers          map[string]string
+	Cookies          []*http.Cookie
+	Raw              []byte         `json:",omitempty"` // Text of binary results
+	Download         *ContentInfo   `json:",omitempty"`
+	Timing           *RequestTiming `json:",omitempty"`
+}
+
+// parseParallelOptions -- parse the options of a PARALLEL block
+func parseParallelOptions(args []string) (parallelOptions, error) {
+	set := NewCmdSet()
+	failFast := set.BoolLong("fail-fast", 0, "Stop the remaining branches when a branch fails")
+	buffer := set.BoolLong("buffer", 0, "Display the output of a branch when it completes")
+	if err := CmdParse(set, append([]string{"PARALLEL"}, args...)); err != nil {
+		return parallelOptions{}, err
+	}
+	if len(set.Args()) > 0 {
+		return parallelOptions{}, errors.New("PARALLEL does not accept arguments")
+	}
+	return parallelOptions{failFast: *failFast, buffer: *buffer}, nil
+}
+
+// executeParallelBlock -- execute each statement of the PARALLEL clause and
+// each BRANCH clause as a branch
+func executeParallelBlock(p *processor, block *controlBlock) error {
+	args, err := p.prepareClause(block.clauses[0])
+	if err != nil {
+		return err
+	}
+	options, err := parseParallelOptions(args)
+	if err != nil {
+		return err
+	}
+
+	branches := make([]parallelBranch, 0)
+	for _, s := range block.clauses[0].body {
+		if s.block == nil && getLineKeyword(s.line.Text) == "" {
+			continue
+		}
+		name := strconv.Itoa(len(branches) + 1)
+		branches = append(branches, parallelBranch{name: name, script: formatStatements([]statement{s})})
+	}
+	for _, clause := range block.clauses[1:] {
+		args, err := p.prepareClause(clause)
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 {
+			return errors.New("BRANCH accepts only a name")
+		}
+		name := strconv.Itoa(len(branches) + 1)
+		if len(args) == 1 {
+			name = args[0]
+		}
+		branches = append(branches, parallelBranch{name: name, script: formatStatements(clause.body)})
+	}
+	return executeParallel(context.Background(), branches, options)
+}
+
+// formatStatements -- the script text of statements and their blocks
+func formatStatements(statements []statement) string {
+	var sb strings.Builder
+	writeStatements(&sb, statements)
+	return sb.String()
+}
+
+func writeStatements(sb *strings.Builder, statements []statement) {
+	for _, s := range statements {
+		if s.block == nil {
+			sb.WriteString(s.line.Text + "\n")
+			continue
+		}
+		for _, clause := range s.block.clauses {
+			sb.WriteString(clause.line.Text + "\n")
+			writeStatements(sb, clause.body)
+		}
+		sb.WriteString(blockDefinitions[s.block.keyword].end() + "\n")
+	}
+}
+
+// executeParallel -- execute the branches in child shells and wait for them
+// to complete; the error has the exit code of the first branch that failed
+func executeParallel(ctx context.Context, branches []parallelBranch, options parallelOptions) error {
+	if len(branches) == 0 {
+		return errors.New("no branches to execute")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to start branches: %s", err.Error())
+	}
+	dir, err := os.MkdirTemp("", "restshell-parallel")
+	if err != nil {
+		return fmt.Errorf("unable to start branches: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	state := captureBranchState()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex // Serializes the output of the branches and the failure order
+	var wg sync.WaitGroup
+	results := make([]branchResult, len(branches))
+	failures := make([]int, 0)
+	startTime := time.Now()
+	for i, branch := range branches {
+		state.Name = branch.name
+		state.Script = branch.script
+		file := filepath.Join(dir, "branch"+strconv.Itoa(i)+".json")
+		if err := saveBranchState(file, state); err != nil {
+			cancel()
+			wg.Wait()
+			return fmt.Errorf("unable to start branch %s: %s", branch.name, err.Error())
+		}
+
+		wg.Add(1)
+		go func(i int, name string, file string) {
+			defer wg.Done()
+			result := runBranchProcess(ctx, exe, name, file, options.buffer, &mutex)
+			mutex.Lock()
+			defer mutex.Unlock()
+			results[i] = result
+			if result.code != ExitSuccess && !result.canceled {
+				failures = append(failures, i)
+				if options.failFast {
+					cancel()
+				}
+			}
+		}(i, branch.name, file)
+	}
+	wg.Wait()
+
+	canceled := 0
+	for i, result := range results {
+		if result.canceled {
+			canceled++
+			fmt.Fprintf(ErrorWriter(), "[%s] canceled\n", branches[i].name)
+		} else if result.err != nil {
+			fmt.Fprintf(ErrorWriter(), "[%s] failed: %s\n", branches[i].name, result.err.Error())
+		} else if result.code != ExitSuccess {
+			fmt.Fprintf(ErrorWriter(), "[%s] failed with exit code %d\n", branches[i].name, result.code)
+		}
+	}
+
+	if !IsCmdSilentEnabled() || IsCmdDebugEnabled() {
+		fmt.Fprintf(OutputWriter(), "Ran %d branches in %s; %d failed, %d canceled\n",
+			len(branches), getDurationString(time.Since(startTime)), len(failures), canceled)
+	}
+
+	if len(failures) > 0 {
+		err := fmt.Errorf("%d of %d branches failed", len(failures), len(branches))
+		return NewExitCodeError(err, results[failures[0]].code)
+	}
+	if canceled > 0 {
+		return NewInterruptError()
+	}
+	return nil
+}
+
+// runBranchProcess -- run a child shell executing the branch of a state file
+func runBranchProcess(ctx context.Context, exe string, name string, file string, buffer bool, mutex *sync.Mutex) branchResult {
+	cmd := exec.CommandContext(ctx, exe, getBranchArguments()...)
+	cmd.Env = append(os.Environ(), ParallelBranchVariable+"="+file)
+
+	var output *lockedBuffer
+	var stdout, stderr *prefixWriter
+	if buffer {
+		output = &lockedBuffer{}
+		cmd.Stdout = output
+		cmd.Stderr = output
+	} else {
+		stdout = newPrefixWriter(OutputWriter(), "["+name+"] ", mutex)
+		stderr = newPrefixWriter(ErrorWriter(), "["+name+"] ", mutex)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+	}
+
+	err := cmd.Run()
+	if buffer {
+		mutex.Lock()
+		fmt.Fprintf(OutputWriter(), "=== %s ===\n", name)
+		OutputWriter().Write(output.Bytes())
+		mutex.Unlock()
+	} else {
+		stdout.Flush()
+		stderr.Flush()
+	}
+
+	if err == nil {
+		return branchResult{code: ExitSuccess}
+	}
+	if ctx.Err() != nil {
+		return branchResult{code: ExitAborted, canceled: true}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return branchResult{code: exitErr.ExitCode()}
+	}
+	return branchResult{code: ExitCommandFailed, err: err}
+}
+
+// getBranchArguments -- the global options of the shell passed to a branch
+func getBranchArguments() []string {
+	args := make([]string, 0)
+	if IsDebugEnabled() {
+		args = append(args, "-d")
+	}
+	if IsVerboseEnabled() {
+		args = append(args, "-v")
+	}
+	if IsSilentEnabled() {
+		args = append(args, "-s")
+	}
+	if IsNetDebugEnabled() {
+		args = append(args, "-n")
+	}
+	return args
+}
+
+// runBranch -- execute a branch in a child shell; the exit code is the
+// error state of the branch
+func runBranch(file string) int {
+	state, err := loadBranchState(file)
+	if err != nil {
+		fmt.Fprintf(ErrorWriter(), "Unable to load parallel branch: %s\n", err.Error())
+		return ExitScriptError
+	}
+	if err := restoreBranchState(state); err != nil {
+		fmt.Fprintf(ErrorWriter(), "Unable to restore parallel branch %s: %s\n", state.Name, err.Error())
+		return ExitScriptError
+	}
+
+	SetGlobal("branch", state.Name)
+	CommandProcessor("", strings.NewReader(state.Script), false, true)
+	return LastError
+}
+
+// captureBranchState -- copy the state of the shell for the branches
+func captureBranchState() branchState {
+	state := branchState{
+		Variables: make(map[string]string),
+		Aliases:   make(map[string]string),
+	}
+	for k, v := range visibleVariables() {
+		if str, ok := v.(string); ok {
+			state.Variables[k] = str
+		}
+	}
+	for k, v := range aliasStore {
+		state.Aliases[k] = v
+	}
+
+	names := make([]string, 0, len(procStore))
+	for name := range procStore {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		proc := procStore[name]
+		ps := procedureState{Name: proc.name, Body: formatStatements(proc.body)}
+		for _, param := range proc.params {
+			ps.Params = append(ps.Params, procParamState{Name: param.name, Value: param.value, Optional: param.optional})
+		}
+		state.Procedures = append(state.Procedures, ps)
+	}
+
+	for _, result := range history {
+		rs := resultState{
+			Text:             result.Text,
+			HttpStatus:       result.HttpStatus,
+			HttpStatusString: result.HttpStatusString,
+			Attempts:         result.Attempts,
+			ContentType:      result.ContentType,
+			Headers:          result.headers,
+			Cookies:          result.cookies,
+			Timing:           result.Timing,
+		}
+		if result.Error != nil {
+			rs.Error = result.Error.Error()
+		}
+		if result.Raw != nil {
+			rs.Text, rs.Raw = "", result.Raw
+		} else if result.Content != nil {
+			rs.Download = result.Content
+		}
+		state.History = append(state.History, rs)
+	}
+
+	state.Jar = GetCookieJar().List("")
+
+	state.Auth = make(map[string]requestAuth)
+	for name, auth := range authContexts {
+		if auth != nil && auth.IsAuthed() {
+			state.Auth[name] = newRequestAuth(auth)
+		}
+	}
+	state.Hooks = make(map[HookEvent][]string)
+	for event, hooks := range hookStore {
+		for _, h := range hooks {
+			if len(h.alias) > 0 {
+				state.Hooks[event] = append(state.Hooks[event], h.alias)
+			}
+		}
+	}
+	return state
+}
+
+// restoreBranchState -- set the state of a child shell for a branch
+func restoreBranchState(state branchState) error {
+	for k, v := range state.Variables {
+		SetGlobal(k, v)
+	}
+	for k, v := range state.Aliases {
+		aliasStore[k] = v
+	}
+
+	for _, ps := range state.Procedures {
+		reader := newScriptReader(strings.NewReader("PROC "+ps.Name+"\n"+ps.Body+"ENDPROC\n"), "")
+		header, _ := reader.ReadLine()
+		block, err := parseBlock(header, reader.ReadLine)
+		if err != nil {
+			return fmt.Errorf("procedure %s: %s", ps.Name, err.Error())
+		}
+		proc := &procedure{name: ps.Name, body: block.clauses[0].body}
+		for _, param := range ps.Params {
+			proc.params = append(proc.params, procParam{name: param.Name, value: param.Value, optional: param.Optional})
+		}
+		procStore[proc.name] = proc
+	}
+
+	for _, rs := range state.History {
+		PushResult(rs.toResult())
+	}
+
+	cookies := make([]*JarCookie, len(state.Jar))
+	for i := range state.Jar {
+		cookies[i] = &state.Jar[i]
+	}
+	GetCookieJar().add(cookies)
+
+	for name, auth := range state.Auth {
+		SetAuthContext(name, auth)
+	}
+	for event, aliases := range state.Hooks {
+		for _, alias := range aliases {
+			if err := RegisterScriptHook(event, alias); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toResult -- rebuild a history result parsing the text, headers and cookies
+func (rs resultState) toResult() Result {
+	result := Result{
+		Text:             rs.Text,
+		HttpStatus:       rs.HttpStatus,
+		HttpStatusString: rs.HttpStatusString,
+		Attempts:         rs.Attempts,
+		headers:          rs.Headers,
+		cookies:          rs.Cookies,
+	}
+	if len(rs.Error) > 0 {
+		result.Error = errors.New(rs.Error)
+	}
+
+	if result.headers == nil {
+		result.headers = make(map[string]string)
+	}
+	result.HeaderMap, _ = NewSimpleHistoryMap(result.headers)
+	if auth, ok := result.headers["Authorization"]; ok {
+		if authmap, err := decodeJwtClaims(auth); err == nil {
+			result.AuthMap = authmap
+		}
+	}
+
+	cookies := make(map[string]string)
+	for _, cookie := range result.cookies {
+		cookies[cookie.Name] = cookie.Value
+	}
+	result.CookieMap, _ = NewSimpleHistoryMap(cookies)
+	if rs.Timing != nil {
+		result.setTiming(*rs.Timing)
+	}
+
+	if rs.Raw != nil {
+		result.Text = string(rs.Raw)
+		result.addParsedContentToResult(rs.ContentType, result.Text)
+	} else if rs.Download != nil {
+		result.ContentType = rs.ContentType
+		result.setContentInfo(*rs.Download)
+	} else if rs.HttpStatus == -1 && len(rs.ContentType) == 0 {
+		result.BodyMap, _ = NewTextHistoryMap(rs.Text)
+	} else {
+		result.addParsedContentToResult(rs.ContentType, rs.Text)
+	}
+	return result
+}
+
+func saveBranchState(file string, state branchState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+func loadBranchState(file string) (branchState, error) {
+	var state branchState
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// prefixWriter -- write complete lines with a prefix to a writer shared by
+// the branches
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	mutex   *sync.Mutex
+	partial []byte
+}
+
+func newPrefixWriter(w io.Writer, prefix string, mutex *sync.Mutex) *prefixWriter {
+	return &prefixWriter{w: w, prefix: prefix, mutex: mutex}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.partial = append(pw.partial, p...)
+	for {
+		index := bytes.IndexByte(pw.partial, '\n')
+		if index < 0 {
+			break
+		}
+		pw.writeLine(pw.partial[:index+1])
+		pw.partial = pw.partial[index+1:]
+	}
+	return len(p), nil
+}
+
+// Flush -- write the last line when it is not terminated
+func (pw *prefixWriter) Flush() {
+	if len(pw.partial) > 0 {
+		pw.writeLine(append(pw.partial, '\n'))
+		pw.partial = nil
+	}
+}
+
+func (pw *prefixWriter) writeLine(line []byte) {
+	pw.mutex.Lock()
+	defer pw.mutex.Unlock()
+	io.WriteString(pw.w, pw.prefix)
+	pw.w.Write(line)
+}
+
+// lockedBuffer -- a buffer receiving the output and error of a branch
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Bytes()
+}
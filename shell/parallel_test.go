@@ -0,0 +1,198 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// authHeaderCommand -- test command displaying the Authorization header an
+// auth context adds to a request
+type authHeaderCommand struct{}
+
+func (a *authHeaderCommand) Execute(args []string) error {
+	auth, err := GetAuthContext(args[0])
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	auth.AddAuth(req)
+	fmt.Fprintf(OutputWriter(), "auth %s\n", req.Header.Get("Authorization"))
+	return nil
+}
+
+func (a *authHeaderCommand) AddOptions(set CmdSet) {
+}
+
+// TestMain -- the test binary is the child shell of parallel branches
+func TestMain(m *testing.M) {
+	AddCommand("authheader", CategoryUtilities, &authHeaderCommand{})
+	if file := os.Getenv(ParallelBranchVariable); len(file) > 0 {
+		os.Exit(runBranch(file))
+	}
+	os.Exit(m.Run())
+}
+
+func captureTestScript(script string) string {
+	var output bytes.Buffer
+	savedOutput, savedError := currentOutput, currentError
+	currentOutput, currentError = &output, &output
+	defer func() {
+		currentOutput, currentError = savedOutput, savedError
+	}()
+
+	runTestScript(script)
+	return output.String()
+}
+
+func verifyOutputContains(t *testing.T, output string, expected ...string) {
+	t.Helper()
+	for _, text := range expected {
+		if !strings.Contains(output, text) {
+			t.Errorf("expected %q in output:\n%s", text, output)
+		}
+	}
+}
+
+func TestParallelBlockCopiesState(t *testing.T) {
+	SetGlobal("partest", "copied")
+	PushText("application/json", `{"id":5}`, nil)
+	script := `
+PROC greet name
+  rem hello %%name%%
+ENDPROC
+PARALLEL
+  rem value %%partest%%
+BRANCH history
+  IF EQ id 5
+    rem found %%branch%%
+  ENDIF
+BRANCH proc
+  greet world
+ENDPARALLEL
+record done
+`
+	output := captureTestScript(script)
+	verifyOutputContains(t, output, "[1] rem value copied", "[history] rem found history", "[proc] rem hello world",
+		"Ran 3 branches")
+	verifyRecorded(t, testRecorder.lines, "done")
+	if LastError != 0 {
+		t.Errorf("expected success but got %d", LastError)
+	}
+}
+
+func TestParallelBranchesAreIsolated(t *testing.T) {
+	script := `
+PARALLEL --buffer
+BRANCH a
+  PROC inbranch
+  ENDPROC
+  rem defined
+END
+`
+	output := captureTestScript(script)
+	verifyOutputContains(t, output, "=== a ===\nrem defined\n")
+	if IsProcedure("inbranch") || GetGlobal("branch") != nil {
+		t.Errorf("expected the branch state not to change the shell")
+	}
+}
+
+func TestParallelWaitsForAllBranches(t *testing.T) {
+	script := `
+PARALLEL
+BRANCH fails
+  exit 3
+BRANCH works
+  rem completed
+END
+`
+	output := captureTestScript(script)
+	verifyOutputContains(t, output, "[works] rem completed", "[fails] failed with exit code 3",
+		"1 of 2 branches failed")
+	if LastError != 3 {
+		t.Errorf("expected exit code 3 but got %d", LastError)
+	}
+}
+
+func TestParallelFailFastCancelsBranches(t *testing.T) {
+	script := `
+PARALLEL --fail-fast
+BRANCH fails
+  exit 2
+BRANCH slow
+  WHILE TRUE
+  ENDWHILE
+END
+`
+	output := captureTestScript(script)
+	verifyOutputContains(t, output, "[slow] canceled", "1 of 2 branches failed")
+	if LastError != 2 {
+		t.Errorf("expected exit code 2 but got %d", LastError)
+	}
+}
+
+func TestRunParallelScripts(t *testing.T) {
+	first := writeTestScript(t, "first.rshell", "rem first %%1%%\n")
+	second := writeTestScript(t, "second.rshell", "rem second %%1%%\n")
+	output := captureTestScript("run --parallel " + first + " " + second + " -- arg\n")
+	verifyOutputContains(t, output, "[first.rshell] rem first arg", "[second.rshell] rem second arg")
+
+	output = captureTestScript("run --parallel --exec \"rem one\" \"rem two\"\n")
+	verifyOutputContains(t, output, "[1] rem one", "[2] rem two", "Ran 2 branches")
+}
+
+func TestBranchStateKeepsCookieJar(t *testing.T) {
+	defer GetCookieJar().Clear("")
+	u, _ := url.Parse("https://example.com/api")
+	GetCookieJar().SetCookies(u, []*http.Cookie{{Name: "session", Value: "s1", Path: "/", Secure: true}})
+
+	file := filepath.Join(t.TempDir(), "branch.json")
+	if err := saveBranchState(file, captureBranchState()); err != nil {
+		t.Fatalf("unable to save the state: %s", err.Error())
+	}
+	GetCookieJar().Clear("")
+	state, err := loadBranchState(file)
+	if err == nil {
+		err = restoreBranchState(state)
+	}
+	if err != nil {
+		t.Fatalf("unable to restore the state: %s", err.Error())
+	}
+	verifyJarCookies(t, GetCookieJar(), "https://example.com/api", "session=s1")
+	verifyJarCookies(t, GetCookieJar(), "http://example.com/api", "")
+}
+
+func TestParallelBranchesKeepAuthAndHooks(t *testing.T) {
+	SetAuthContext("partest", NewJwtHeaderAuth("token"))
+	defer delete(authContexts, "partest")
+	addTestScriptHook(t, HookPreCommand, "parhook", "rem hooked")
+
+	script := `
+PARALLEL --buffer
+BRANCH a
+  authheader partest
+END
+`
+	output := captureTestScript(script)
+	verifyOutputContains(t, output, "=== a ===\nrem hooked\nauth Bearer token\n")
+	if LastError != 0 {
+		t.Errorf("expected success but got %d", LastError)
+	}
+}
+
+func TestPrefixWriterWritesCompleteLines(t *testing.T) {
+	var output bytes.Buffer
+	w := newPrefixWriter(&output, "[x] ", &sync.Mutex{})
+	w.Write([]byte("one\ntw"))
+	w.Write([]byte("o\nthree"))
+	w.Flush()
+	if output.String() != "[x] one\n[x] two\n[x] three\n" {
+		t.Errorf("unexpected output: %q", output.String())
+	}
+}
@@ -0,0 +1,294 @@
+// Procedures
+//
+// A procedure is a named block of script lines defined with PROC/ENDPROC
+// and executed with the CALL command or by using its name as a command.
+//
+//	PROC name [param ...] [param=default ...]
+//	  ...
+//	  RETURN [value]
+//	ENDPROC
+//
+// Arguments are bound to the parameters by position or by name (name=value)
+// and are local variables of the procedure.
+
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultProcResultVariable -- variable receiving the value returned by a procedure
+var DefaultProcResultVariable = "$return"
+
+// MaxProcDepth -- maximum depth of nested procedure calls
+var MaxProcDepth = 32
+
+var procStore = make(map[string]*procedure)
+var procDepth = 0
+
+// procParam -- a procedure parameter with an optional default value
+type procParam struct {
+	name     string
+	value    string
+	optional bool
+}
+
+// procedure -- a user defined procedure
+type procedure struct {
+	name   string
+	params []procParam
+	body   []statement
+}
+
+// executeProcBlock -- define the procedure of a PROC block
+func executeProcBlock(p *processor, block *controlBlock) error {
+	args, err := p.prepareClause(block.clauses[0])
+	if err != nil {
+		return err
+	}
+
+	proc, err := newProcedure(args)
+	if err != nil {
+		return err
+	}
+	proc.body = block.clauses[0].body
+	procStore[proc.name] = proc
+	return nil
+}
+
+func newProcedure(args []string) (*procedure, error) {
+	if len(args) == 0 {
+		return nil, errors.New("PROC requires a procedure name")
+	}
+
+	name := strings.ToUpper(args[0])
+	if !IsValidKey(name) || strings.Contains(name, ".") {
+		return nil, fmt.Errorf("invalid procedure name: %s", args[0])
+	}
+	if _, ok := cmdMap[name]; ok || isBlockKeyword(name) || isBlockTerminator(name) || isReservedCommand(name) {
+		return nil, fmt.Errorf("procedure name conflicts with a command: %s", args[0])
+	}
+
+	proc := &procedure{name: name}
+	for _, arg := range args[1:] {
+		param := procParam{name: arg}
+		if k, v, ok := strings.Cut(arg, "="); ok {
+			param = procParam{name: k, value: v, optional: true}
+		}
+		if !IsValidKey(param.name) {
+			return nil, fmt.Errorf("invalid parameter name: %s", param.name)
+		}
+		if proc.hasParam(param.name) {
+			return nil, fmt.Errorf("duplicate parameter name: %s", param.name)
+		}
+		proc.params = append(proc.params, param)
+	}
+	return proc, nil
+}
+
+func (proc *procedure) hasParam(name string) bool {
+	for _, param := range proc.params {
+		if param.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bindArguments -- bind named arguments (name=value) and then positional
+// arguments to the parameters in order; unbound parameters use defaults
+func (proc *procedure) bindArguments(args []string) (map[string]string, error) {
+	values := make(map[string]string)
+	positional := make([]string, 0)
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok && proc.hasParam(k) {
+			values[k] = v
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	next := 0
+	for _, param := range proc.params {
+		if _, ok := values[param.name]; ok {
+			continue
+		}
+		if next < len(positional) {
+			values[param.name] = positional[next]
+			next++
+		} else if param.optional {
+			values[param.name] = param.value
+		} else {
+			return nil, fmt.Errorf("missing argument for parameter: %s", param.name)
+		}
+	}
+
+	if next < len(positional) {
+		return nil, fmt.Errorf("too many arguments for procedure %s", proc.name)
+	}
+	return values, nil
+}
+
+// Usage -- the usage line of the procedure
+func (proc *procedure) Usage() string {
+	var sb strings.Builder
+	sb.WriteString(proc.name)
+	for _, param := range proc.params {
+		sb.WriteString(" ")
+		if param.optional {
+			sb.WriteString("[" + param.name + "=" + param.value + "]")
+		} else {
+			sb.WriteString(param.name)
+		}
+	}
+	return sb.String()
+}
+
+// execute -- execute the procedure with the arguments returning the number of
+// commands executed and the value returned by the procedure
+func (proc *procedure) execute(args []string, singleStep bool) (int, string, error) {
+	values, err := proc.bindArguments(args)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if procDepth >= MaxProcDepth {
+		return 0, "", errors.New("too many nested procedure calls")
+	}
+	procDepth++
+	defer func() { procDepth-- }()
+
+	defer PushScope("proc:" + proc.name)()
+	for k, v := range values {
+		SetLocal(k, v)
+	}
+
+	frame, pop := pushCallFrame("proc " + proc.name)
+	defer pop()
+
+	p := newProcessor("", true)
+	p.canReturn = true
+	p.frame = frame
+	if singleStep {
+		startStepping()
+	}
+	p.executeStatements(proc.body)
+	if p.exitErr != nil {
+		return p.count, "", p.exitErr
+	}
+	if p.trapped != nil {
+		return p.count, "", p.trapped
+	}
+	if p.interrupted {
+		return p.count, "", p.abortErr
+	}
+	return p.count, p.returnValue, nil
+}
+
+// IsProcedure -- true if a procedure with the name is defined
+func IsProcedure(name string) bool {
+	_, ok := procStore[strings.ToUpper(name)]
+	return ok
+}
+
+// GetAllProcNames -- get the sorted list of procedure names
+func GetAllProcNames() []string {
+	list := make([]string, 0, len(procStore))
+	for name := range procStore {
+		list = append(list, name)
+	}
+	return SortedStringSlice(list)
+}
+
+// GetProcUsage -- get the usage line of a procedure
+func GetProcUsage(name string) (string, error) {
+	proc, ok := procStore[strings.ToUpper(name)]
+	if !ok {
+		return "", fmt.Errorf("procedure not found: %s", name)
+	}
+	return proc.Usage(), nil
+}
+
+// RemoveProcedure -- remove a procedure definition
+func RemoveProcedure(name string) error {
+	name = strings.ToUpper(name)
+	if _, ok := procStore[name]; !ok {
+		return fmt.Errorf("procedure not found: %s", name)
+	}
+	delete(procStore, name)
+	return nil
+}
+
+// isReservedCommand -- commands handled by the command processor itself
+func isReservedCommand(name string) bool {
+	switch name {
+	case "Q", "QUIT", "SHELL", "HELP", "RETURN":
+		return true
+	}
+	return false
+}
+
+// CallCommand -- execute a procedure
+type CallCommand struct {
+	resultOption *string
+	stepOption   *bool
+	listOption   *bool
+	count        int
+}
+
+func NewCallCommand() *CallCommand {
+	return &CallCommand{}
+}
+
+func (cmd *CallCommand) AddOptions(set CmdSet) {
+	set.SetParameters("procedure [args...]")
+	cmd.resultOption = set.StringLong("result", 'r', DefaultProcResultVariable, "Variable to receive the value returned", "var")
+	cmd.stepOption = set.BoolLong("step", 0, "Single step through the procedure")
+	cmd.listOption = set.BoolLong("list", 0, "List the defined procedures")
+	AddCommonCmdOptions(set, CmdDebug, CmdVerbose)
+}
+
+func (cmd *CallCommand) Execute(args []string) error {
+	cmd.count = 0
+	if *cmd.listOption {
+		for _, name := range GetAllProcNames() {
+			fmt.Fprintf(OutputWriter(), "%s\n", procStore[name].Usage())
+		}
+		return nil
+	}
+
+	if len(args) == 0 {
+		return errors.New("specify a procedure to call")
+	}
+
+	proc, ok := procStore[strings.ToUpper(args[0])]
+	if !ok {
+		return fmt.Errorf("procedure not found: %s", args[0])
+	}
+
+	resultVar := *cmd.resultOption
+	if !IsValidKey(resultVar) {
+		return ErrInvalidKey
+	}
+
+	count, value, err := proc.execute(args[1:], *cmd.stepOption)
+	cmd.count = count
+	if err != nil {
+		return err
+	}
+	return SetGlobal(resultVar, value)
+}
+
+func (cmd *CallCommand) DoNotCount() bool {
+	return true
+}
+
+func (cmd *CallCommand) DoNotClearError() bool {
+	return true
+}
+
+func (cmd *CallCommand) CommandCount() int {
+	return cmd.count
+}
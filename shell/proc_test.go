@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestProcedureWithParameters(t *testing.T) {
+	script := `
+PROC greet name greeting=hello
+  record %%greeting%% %%name%%
+ENDPROC
+greet world
+CALL greet greeting=hi bob
+greet name=amy bye
+`
+	verifyRecorded(t, runTestScript(script), "hello world", "hi bob", "bye amy")
+}
+
+func TestProcedureReturnValue(t *testing.T) {
+	script := `
+PROC double value
+  IF EQ --var value 2
+    RETURN 4
+  ENDIF
+  record not returned
+  RETURN unknown
+  record never
+ENDPROC
+CALL --result answer double 2
+record %%answer%%
+double 3
+record %%$return%%
+`
+	verifyRecorded(t, runTestScript(script), "4", "not returned", "unknown")
+}
+
+func TestProcedureParametersAreScoped(t *testing.T) {
+	SetGlobal("scoped", "outer")
+	RemoveGlobal("unset")
+	script := `
+PROC scope scoped unset=x
+  record %%scoped%% %%unset%%
+ENDPROC
+scope inner
+record %%scoped%%
+`
+	verifyRecorded(t, runTestScript(script), "inner x", "outer")
+	if GetGlobal("unset") != nil {
+		t.Errorf("expected the parameter to be removed after the call")
+	}
+}
+
+func TestProcedureArgumentErrors(t *testing.T) {
+	proc, err := newProcedure([]string{"errors", "a", "b=1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if _, err := proc.bindArguments([]string{}); err == nil {
+		t.Errorf("expected an error for a missing argument")
+	}
+	if _, err := proc.bindArguments([]string{"1", "2", "3"}); err == nil {
+		t.Errorf("expected an error for too many arguments")
+	}
+	if values, err := proc.bindArguments([]string{"b=2", "1"}); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	} else if values["a"] != "1" || values["b"] != "2" {
+		t.Errorf("unexpected bindings: %v", values)
+	}
+}
+
+func TestProcedureNameConflicts(t *testing.T) {
+	for _, name := range []string{"run", "if", "endif", "return", "bad.name"} {
+		if _, err := newProcedure([]string{name}); err == nil {
+			t.Errorf("expected an error for procedure name %s", name)
+		}
+	}
+}
+
+func TestReturnOutsideProcedure(t *testing.T) {
+	script := `
+record before
+RETURN
+`
+	verifyRecorded(t, runTestScript(script), "before")
+	if LastError == 0 {
+		t.Errorf("expected an error for RETURN outside a procedure")
+	}
+}
@@ -37,78 +37,252 @@ func CommandProcessor(defaultPrompt string, reader io.Reader, singleStep bool, a
 		singleStep = false
 	}
 
-	var quit bool
-	var count int
-	var shell = ""
-	var prompt = defaultPrompt
-
-	scanner := bufio.NewScanner(reader)
-	for writePrompt(!quit, prompt); !quit && scanner.Scan(); writePrompt(!quit, prompt) {
-		line, err := NewCommandLine(scanner.Text(), shell)
-		if err != nil {
-			LastError = 1
-			fmt.Fprintf(ErrorWriter(), "%s: %s\n", "Line Parse Error", err.Error())
-			continue
+	p := newProcessor(defaultPrompt, allowAbort)
+	if singleStep {
+		startStepping()
+	}
+	success := p.process(reader, "")
+	return p.count, success
+}
+
+// processScript -- execute a script on behalf of a command (e.g. RUN) and return
+// the number of commands executed, the value returned by the script (nil without
+// a RETURN) and an error trapped for an enclosing TRY block or an EXIT of the shell.
+// The file names the script in error messages and the call stack.
+func processScript(reader io.Reader, file string, singleStep bool) (int, *string, error) {
+	p := newProcessor("", true)
+	p.canReturn = true
+	frame, pop := pushCallFrame(strings.TrimSpace("run " + file))
+	defer pop()
+	p.frame = frame
+	if singleStep {
+		startStepping()
+	}
+	if !p.process(reader, file) {
+		return p.count, nil, newScriptError(errors.New("Command processor failed"))
+	}
+	if p.trapped != nil {
+		return p.count, nil, p.trapped
+	}
+	if IsFlowControl(p.exitErr, FlowExit) {
+		return p.count, nil, p.exitErr
+	}
+	if p.returned {
+		return p.count, &p.returnValue, nil
+	}
+	return p.count, nil, nil
+}
+
+// process -- read and execute commands until the end of input or the processor
+// is stopped; returns false if reading the input failed
+func (p *processor) process(reader io.Reader, file string) bool {
+	if editor, ok := reader.(*lineEditor); ok {
+		p.editor = editor
+	}
+	source := newScriptReader(reader, file)
+	source.prompt = p.writeContinuationPrompt
+	next := func() (scriptLine, bool) {
+		p.writeContinuationPrompt()
+		return source.ReadLine()
+	}
+
+	for p.writePrompt(); !p.quit && !p.returned && p.trapped == nil; p.writePrompt() {
+		p.interrupted = false
+		line, ok := source.ReadLine()
+		if !ok {
+			break
 		}
 
-		switch line.Command {
-		case "":
-		case "QUIT":
-			fallthrough
-		case "Q":
-			if len(shell) == 0 {
-				quit = true
-			} else {
-				prompt = defaultPrompt
-				shell = ""
+		if keyword := getLineKeyword(line.Text); len(p.shell) == 0 && isBlockKeyword(keyword) {
+			block, err := parseBlock(line, next)
+			if err != nil {
+				p.setLine(line)
+				p.reportError(keyword, newScriptError(err))
+				continue
 			}
-		case "SHELL":
-			if len(line.ArgString) > 0 {
-				shell = line.ArgString
-				if !(shell[len(shell)-1] == '\\' || shell[len(shell)-1] == '/') {
-					shell = shell + " "
-				}
-				prompt = defaultPrompt + shell
-			} else {
-				shell = ""
-				prompt = defaultPrompt
+			p.executeBlock(block)
+		} else {
+			p.executeLine(line)
+		}
+	}
+	if err := source.Err(); err != nil {
+		fmt.Fprintf(ErrorWriter(), "Scanner error %s\n", err.Error())
+		return false
+	}
+	return true
+}
+
+// processor -- state of a command processor while executing a stream of commands
+type processor struct {
+	defaultPrompt string
+	prompt        string
+	shell         string
+	allowAbort    bool
+	quit          bool
+	interrupted   bool  // A command aborted; unwind the executing blocks
+	abortErr      error // The error of the aborted command
+	trapped       error // An error trapped for a TRY block; unwind the executing blocks
+	exitErr       error // The EXIT request ending the processor
+	canReturn     bool  // RETURN is supported (e.g. procedures)
+	returned      bool
+	returnValue   string
+	count         int
+	line          scriptLine  // The line executing
+	frame         *callFrame  // The call stack frame of a script or procedure
+	editor        *lineEditor // The line editor of an interactive session displays the prompts
+}
+
+func newProcessor(defaultPrompt string, allowAbort bool) *processor {
+	return &processor{
+		defaultPrompt: defaultPrompt,
+		prompt:        defaultPrompt,
+		allowAbort:    allowAbort,
+	}
+}
+
+// setLine -- record the line executing for error messages and the call stack
+func (p *processor) setLine(line scriptLine) {
+	p.line = line
+	if p.frame != nil {
+		p.frame.line = line
+	}
+}
+
+// executeLine -- parse and execute a single script line
+func (p *processor) executeLine(input scriptLine) {
+	p.setLine(input)
+	line, err := NewCommandLine(input.Text, p.shell)
+	if err != nil {
+		LastError = ExitScriptError
+		fmt.Fprintf(ErrorWriter(), "%s%s: %s\n", p.locationPrefix(), "Line Parse Error", err.Error())
+		return
+	}
+
+	switch line.Command {
+	case "":
+	case "QUIT":
+		fallthrough
+	case "Q":
+		if len(p.shell) == 0 {
+			p.quit = true
+		} else {
+			p.prompt = p.defaultPrompt
+			p.shell = ""
+		}
+	case "SHELL":
+		if len(line.ArgString) > 0 {
+			p.shell = line.ArgString
+			if !(p.shell[len(p.shell)-1] == '\\' || p.shell[len(p.shell)-1] == '/') {
+				p.shell = p.shell + " "
 			}
-		default:
-			if !line.IsComment {
-				cmd, err, contStepping := processCommand(line, singleStep)
-				singleStep = contStepping
-				if IsFlowControl(err, FlowQuit) {
-					quit = true
-				} else if err != nil {
-					LastError = 1
-					fmt.Fprintf(ErrorWriter(), "%s: %s\n", line.Command, err.Error())
-					if IsFlowControl(err, FlowAbort) && allowAbort {
-						quit = true
-					}
-				} else if track, trackable := cmd.(Trackable); cmd != nil && trackable {
-					if !track.DoNotCount() {
-						count++
-					}
-					if !track.DoNotClearError() {
-						LastError = 0
-					}
-					count = count + track.CommandCount()
-				} else {
+			p.prompt = p.defaultPrompt + p.shell
+		} else {
+			p.shell = ""
+			p.prompt = p.defaultPrompt
+		}
+	case "RETURN":
+		if !p.canReturn {
+			p.reportError(line.Command, newScriptError(errors.New("RETURN is only supported in a procedure or script")))
+			return
+		}
+		p.returned = true
+		p.returnValue = strings.Join(line.GetTokens()[1:], " ")
+	default:
+		if isBlockTerminator(line.Command) {
+			p.reportError(line.Command, newScriptError(errors.New("no matching block for "+line.Command)))
+			return
+		}
+
+		if !line.IsComment {
+			cmd, err := processCommand(line, scriptDebugger.shouldStop(input, line.Command))
+			if err != nil {
+				p.reportError(line.Command, err)
+			} else if track, trackable := cmd.(Trackable); cmd != nil && trackable {
+				if !track.DoNotCount() {
+					p.count++
+				}
+				if !track.DoNotClearError() {
 					LastError = 0
-					count++
 				}
+				p.count = p.count + track.CommandCount()
+			} else {
+				LastError = 0
+				p.count++
+			}
 
-				if DoesCommandRequestQuit(cmd) {
-					quit = true
-				}
+			if DoesCommandRequestQuit(cmd) {
+				p.quit = true
 			}
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(ErrorWriter(), "Scanner error %s\n", err.Error())
-		return count, false
+}
+
+// reportError -- apply the processor error handling for an error returned by a command
+func (p *processor) reportError(command string, err error) {
+	if IsFlowControl(err, FlowQuit) {
+		p.quit = true
+		return
+	}
+
+	// EXIT ends the processor and the processors of the calling scripts
+	if isExitRequest(err) {
+		LastError = GetExitCode(err)
+		p.quit = true
+		p.exitErr = err
+		return
+	}
+
+	// Report the command that failed inside a block or nested script
+	location := p.line.Location()
+	if cmdErr, ok := err.(CommandError); ok {
+		command, location, err = cmdErr.Command, cmdErr.Location, cmdErr.Err
+	}
+
+	LastError = GetExitCode(err)
+	if trapDepth > 0 && !IsInterrupt(err) {
+		p.trapped = newCommandError(command, err, location)
+		return
+	}
+
+	prefix := ""
+	if len(location) > 0 {
+		prefix = location + ": "
+	}
+	fmt.Fprintf(ErrorWriter(), "%s%s: %s\n", prefix, command, err.Error())
+	if IsFlowControl(err, FlowAbort) {
+		traceAbort(err)
+		p.interrupted = true
+		p.abortErr = err
+		if p.allowAbort {
+			p.quit = true
+		}
+	}
+}
+
+// locationPrefix -- the file:line prefix of messages for the line executing
+func (p *processor) locationPrefix() string {
+	if location := p.line.Location(); len(location) > 0 {
+		return location + ": "
+	}
+	return ""
+}
+
+func (p *processor) writePrompt() {
+	p.showPrompt(p.prompt)
+}
+
+func (p *processor) writeContinuationPrompt() {
+	if len(p.prompt) > 0 {
+		p.showPrompt(strings.Repeat(".", len(strings.TrimSpace(p.prompt))) + " ")
+	}
+}
+
+func (p *processor) showPrompt(prompt string) {
+	if p.editor != nil {
+		p.editor.SetPrompt(prompt)
+		return
 	}
-	return count, true
+	writePrompt(!p.quit, prompt)
 }
 
 func writePrompt(doPrompt bool, prompt string) {
@@ -117,30 +291,51 @@ func writePrompt(doPrompt bool, prompt string) {
 	}
 }
 
-// processCommand - Identify command and process it in the context of built in debugger (singlestep)
-func processCommand(line *Line, singleStep bool) (Command, error, bool) {
+// processCommand - Identify command and process it in the context of built in debugger
+// when the debugger stops at the line
+func processCommand(line *Line, debug bool) (Command, error) {
 
-	if line.Echo || singleStep {
+	if line.Echo {
 		fmt.Println(line.CmdLine)
 	}
 
 	cmd, tokens, err := getCmdAndArgs(line)
 	if err != nil {
-		return cmd, err, singleStep
+		return cmd, err
 	}
 
-	if singleStep {
-		switch doSingleStep(cmd) {
-		case "q":
-			return cmd, NewFlowError("Quit requested", FlowQuit), singleStep
-		case "g":
-			singleStep = false
-		default:
+	if debug && !isDebugExempt(cmd) {
+		if err := scriptDebugger.prompt(); err != nil {
+			return cmd, err
 		}
 	}
 
-	err = processCmd(cmd, tokens, line.Echo || singleStep)
-	return cmd, err, singleStep
+	err = processCmd(cmd, tokens, line.Echo)
+	return cmd, err
+}
+
+// ExecuteCommandLine -- parse and execute a single command line on behalf of
+// another command; block commands are not supported
+func ExecuteCommandLine(input string) error {
+	line, err := NewCommandLine(input, "")
+	if err != nil {
+		return err
+	}
+
+	if line.IsComment || len(line.Command) == 0 {
+		return nil
+	}
+
+	if isBlockKeyword(line.Command) || isBlockTerminator(line.Command) {
+		return errors.New(line.Command + " cannot be executed as a single command")
+	}
+
+	if line.Command == "QUIT" || line.Command == "Q" || line.Command == "SHELL" {
+		return errors.New(line.Command + " cannot be executed as a single command")
+	}
+
+	_, err = processCommand(line, false)
+	return err
 }
 
 func getCmdAndArgs(line *Line) (cmd Command, tokens []string, err error) {
@@ -159,21 +354,16 @@ func getCmdAndArgs(line *Line) (cmd Command, tokens []string, err error) {
 		} else {
 			tokens = line.GetCmdAndArguments()
 		}
+	} else if IsProcedure(line.Command) {
+		// Procedures are executed by name using CALL
+		cmd = cmdMap["CALL"]
+		tokens = append([]string{"CALL", "--"}, line.GetCmdAndArguments()...)
 	} else {
-		err = errors.New("Invalid Command '" + line.Command + "'. Try 'help'")
+		err = newScriptError(errors.New("Invalid Command '" + line.Command + "'. Try 'help'"))
 	}
 	return
 }
 
-// doSingleStep - Unless a command exempt from single stepping prmopt for action
-func doSingleStep(cmd Command) string {
-	if DoesCommandRequestNoStep(cmd) {
-		return ""
-	} else {
-		return getStepCommand()
-	}
-}
-
 // parseAndExecute - Parse options and execute the command
 func parseAndExecute(cmd Command, command string, tokens []string) error {
 	// Strip out sub command before parsing; add it back with arguments
@@ -184,7 +374,7 @@ func parseAndExecute(cmd Command, command string, tokens []string) error {
 		if len(tokens) > 1 && !strings.HasPrefix(tokens[1], "-") {
 			subCommand = strings.ToUpper(tokens[1])
 			if !ContainsCommand(subCommand, subCommands) {
-				return errors.New("Invalid sub-command: " + subCommand)
+				return newScriptError(errors.New("Invalid sub-command: " + subCommand))
 			}
 			parseTokens = makeSubTokenArray(command, tokens[2:])
 		}
@@ -200,7 +390,7 @@ func parseAndExecute(cmd Command, command string, tokens []string) error {
 	if err != nil {
 		fmt.Fprintln(ErrorWriter(), err.Error())
 		set.Usage()
-		return errors.New("invalid arguments")
+		return newScriptError(errors.New("invalid arguments"))
 	}
 	if IsCmdHelpEnabled() {
 		set.Usage()
@@ -234,11 +424,14 @@ func processCmd(cmd Command, tokens []string, echoed bool) (result error) {
 	defer func() {
 		signal.Stop(sigchan)
 		if interrupted {
-			result = NewFlowError("Command interrupted", FlowAbort)
+			result = NewInterruptError()
 			_ = recover()
 		} else if r := recover(); r != nil {
-			result = errors.New("Command failed")
+			result = NewExitCodeError(errors.New("Command failed"), ExitPanic)
 			message := fmt.Sprintf("Exception processing %s command", command)
+			if location := currentLocation(); len(location) > 0 {
+				message = message + " at " + location
+			}
 			fmt.Fprintln(ErrorWriter(), message)
 			fmt.Fprintf(ErrorWriter(), "Panic: %v\n%s\n", r, debug.Stack())
 		}
@@ -256,10 +449,18 @@ func processCmd(cmd Command, tokens []string, echoed bool) (result error) {
 		}
 	}()
 
+	if err := runCommandHooks(HookPreCommand, tokens, nil); err != nil {
+		return err
+	}
 	if c, ok := cmd.(LineProcessor); ok {
-		return c.ExecuteLine(tokens[1], echoed)
+		result = c.ExecuteLine(tokens[1], echoed)
+	} else {
+		result = parseAndExecute(cmd, command, tokens)
+	}
+	if err := runCommandHooks(HookPostCommand, tokens, result); err != nil && result == nil {
+		result = err
 	}
-	return parseAndExecute(cmd, command, tokens)
+	return result
 }
 
 func validateCmd(input string) error {
@@ -305,7 +506,7 @@ func validateCmd(input string) error {
 	set.Reset()
 	err = CmdParse(set, tokens)
 	if err != nil {
-		return errors.New("invalid arguments")
+		return fmt.Errorf("invalid arguments: %s", err.Error())
 	}
 	return nil
 }
@@ -354,20 +555,3 @@ func GetLine(prompt string) string {
 	}
 	return ""
 }
-
-// getStepCommand - Prompt for stepping commands g, q, or empty
-func getStepCommand() string {
-	var stepCmd string
-	fmt.Print("Stopped> ")
-	fmt.Scanln(&stepCmd)
-	stepCmd = strings.ToLower(strings.TrimSpace(stepCmd))
-	if len(stepCmd) == 0 {
-		return ""
-	}
-	if stepCmd == "g" {
-		return "g"
-	} else if stepCmd == "q" {
-		return "q"
-	}
-	return ""
-}
@@ -9,8 +9,10 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,12 +23,22 @@ type RestClient struct {
 	OutputRequest bool
 	Headers       []string
 	Client        *http.Client
+	Retry         RetryPolicy
+	configErr     error // Error of the TLS or retry configuration returned by requests
 }
 
 // RestResponse -- The response structure returned by a REST interface
 type RestResponse struct {
 	Text     string
 	httpResp *http.Response
+	attempts int
+	timing   RequestTiming
+}
+
+// newRestResponse -- a response of a request whose body was read; ends the
+// timing of the request
+func newRestResponse(text string, resp *http.Response, trace *requestTrace) *RestResponse {
+	return &RestResponse{Text: text, httpResp: resp, attempts: trace.attempts, timing: trace.finish()}
 }
 
 func NewRestClient() RestClient {
@@ -51,34 +63,116 @@ func NewRestClientFromOptions() RestClient {
 		},
 	}
 
-	// Create non-default transport to break connecxtion pooling
+	// Create non-default transport to break connecxtion pooling; an invalid TLS
+	// configuration fails the requests of the client
 	if IsCmdReconnectEnabled() {
+		tlsConfig, err := NewTLSConfig(GetCmdTLSSettings())
+		if err != nil {
+			client.configErr = err
+		}
 		client.Client.Transport = &http.Transport{
+			Proxy:                 GetCmdProxyFunc(),
+			TLSClientConfig:       tlsConfig,
 			MaxIdleConns:          1000,
+			MaxIdleConnsPerHost:   1000,
 			IdleConnTimeout:       90 * time.Second,
 			TLSHandshakeTimeout:   10 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
 		}
+	} else if IsCmdProxyConfigured() || !GetCmdTLSSettings().IsDefault() {
+		transport, err := getCachedTransport(GetCmdTLSSettings(), GetCmdProxyValue(), GetCmdNoProxyValue())
+		if err != nil {
+			client.configErr = err
+		} else {
+			client.Client.Transport = transport
+		}
 	}
 
-	if IsCmdLocalCertsEnabled() {
-		newTlsWithLocalCertificates(client.Client)
-	}
-	if IsCmdSkipCertValidationEnabled() {
-		client.DisableCertValidation()
-	}
 	if IsCmdNoRedirectEnabled() {
 		client.DisableRedirect()
 	}
 
-	if transport, ok := client.Client.Transport.(*http.Transport); ok {
-		transport.MaxIdleConnsPerHost = 1000
+	if IsCmdCookieJarEnabled() {
+		client.Client.Jar = GetCookieJar()
 	}
 
+	// An invalid retry policy fails the requests of the client as well
+	retry, err := GetCmdRetryPolicy()
+	if err != nil && client.configErr == nil {
+		client.configErr = err
+	}
+	client.Retry = retry
+
 	client.Headers = GetCmdHeaderValues()
 	return client
 }
 
+// transportKey -- the settings of a shared transport and the versions of its
+// certificate files
+type transportKey struct {
+	tls     TLSSettings
+	proxy   string
+	noProxy string
+	files   string
+}
+
+var transportCache = make(map[transportKey]*http.Transport)
+var transportMutex sync.Mutex
+
+// getCachedTransport -- the transport of TLS and proxy settings; the commands
+// with the same settings share a transport to reuse its connections instead
+// of loading the certificates and opening connections for every command. A
+// certificate file changed on disk replaces the transport of the settings.
+func getCachedTransport(settings TLSSettings, proxy string, noProxy string) (*http.Transport, error) {
+	key := transportKey{tls: settings, proxy: proxy, noProxy: noProxy, files: getCertificateVersions(settings)}
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	if transport, ok := transportCache[key]; ok {
+		return transport, nil
+	}
+	for k, transport := range transportCache {
+		if k.tls == key.tls && k.proxy == key.proxy && k.noProxy == key.noProxy {
+			transport.CloseIdleConnections()
+			delete(transportCache, k)
+		}
+	}
+
+	tlsConfig, err := NewTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	proxyFunc, err := NewProxyFunc(proxy, noProxy)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConnsPerHost = 1000
+	transportCache[key] = transport
+	return transport, nil
+}
+
+// getCertificateVersions -- the modification time and size of the certificate
+// files of the settings; missing files have no version
+func getCertificateVersions(settings TLSSettings) string {
+	files := []string{settings.ClientCert, settings.ClientKey, settings.CABundle}
+	if settings.LocalCerts {
+		files = append(files, filepath.Join(GetInitDirectory(), "cert.pem"), filepath.Join(GetExeDirectory(), "cert.pem"))
+	}
+
+	versions := make([]string, len(files))
+	for i, file := range files {
+		if len(file) == 0 {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			versions[i] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return strings.Join(versions, ",")
+}
+
 func (r *RestClient) DisableRedirect() {
 	r.Client.CheckRedirect = func(_ *http.Request, _ []*http.Request) error { return http.ErrUseLastResponse }
 }
@@ -87,18 +181,24 @@ func (r *RestClient) EnableRedirect() {
 	r.Client.CheckRedirect = nil
 }
 
-// DisableCertValidation -- A function to disable cert validation in the rest client
-// TODO: cleanup relationship be between skipping verification and including certificates
+// DisableCertValidation -- A function to disable cert validation in the rest client;
+// a transport shared with other clients is copied
 func (r *RestClient) DisableCertValidation() {
 	if t, ok := r.Client.Transport.(*http.Transport); ok {
-		if t.TLSClientConfig != nil {
-			t.TLSClientConfig.InsecureSkipVerify = true
-			return
+		t = t.Clone()
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
 		}
+		t.TLSClientConfig.InsecureSkipVerify = true
+		r.Client.Transport = t
+		return
 	}
 
-	r.Client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
-
+	// The client uses the default transport; keep the proxy settings of the command
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = GetCmdProxyFunc()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	r.Client.Transport = transport
 }
 
 func (r *RestClient) DoGet(authContext Auth, url string) (resultResponse *RestResponse, resultError error) {
@@ -106,9 +206,58 @@ func (r *RestClient) DoGet(authContext Auth, url string) (resultResponse *RestRe
 }
 
 func (r *RestClient) DoMethod(method string, authContext Auth, url string) (resultResponse *RestResponse, resultError error) {
+	resp, trace, err := r.sendMethodRequest(method, authContext, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New("unable to get content, " + err.Error())
+	}
+
+	return newRestResponse(string(body), resp, trace), nil
+}
+
+// DoMethodToWriter -- Perform an HTTP method request streaming the body of a
+// successful (2xx) response to the writer returned by open; the body of other
+// responses is read into the response text. Returns the bytes written.
+func (r *RestClient) DoMethodToWriter(method string, authContext Auth, url string, open func(*http.Response) (io.Writer, error)) (*RestResponse, int64, error) {
+	resp, trace, err := r.sendMethodRequest(method, authContext, url)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, errors.New("unable to get content, " + err.Error())
+		}
+		return newRestResponse(string(body), resp, trace), 0, nil
+	}
+
+	w, err := open(resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	written, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return nil, written, errors.New("unable to get content, " + err.Error())
+	}
+	return newRestResponse("", resp, trace), written, nil
+}
+
+// sendMethodRequest -- send a request without a body; the caller closes the
+// body of the response. Returns the trace of the attempts.
+func (r *RestClient) sendMethodRequest(method string, authContext Auth, url string) (*http.Response, *requestTrace, error) {
+	if r.configErr != nil {
+		return nil, nil, r.configErr
+	}
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
-		return nil, errors.New("Building request: " + err.Error())
+		return nil, nil, errors.New("Building request: " + err.Error())
 	}
 	if authContext != nil {
 		authContext.AddAuth(req)
@@ -123,8 +272,12 @@ func (r *RestClient) DoMethod(method string, authContext Auth, url string) (resu
 	contentType := "application/json"
 	addDefaultContentType(req, contentType)
 
+	if err := RunRequestHooks(req); err != nil {
+		return nil, nil, err
+	}
+
 	if r.Debug {
-		fmt.Fprintf(OutputWriter(), "Executing: (GET) %s\n", req.URL.String())
+		fmt.Fprintf(OutputWriter(), "Executing: (%s) %s\n", method, req.URL.String())
 		fmt.Fprintln(OutputWriter(), "Sending Headers:")
 		dumpHeaders(OutputWriter(), req)
 
@@ -132,25 +285,33 @@ func (r *RestClient) DoMethod(method string, authContext Auth, url string) (resu
 		for _, c := range req.Cookies() {
 			fmt.Fprintf(OutputWriter(), "%s=%s\n", c.Name, c.Value)
 		}
-	}
-
-	resp, err := r.Client.Do(req)
-	if err != nil {
-		errMsg := "response returned error, " + err.Error()
-		if r.Debug {
-			fmt.Fprintln(OutputWriter(), errMsg)
+		if r.Client.Jar != nil {
+			for _, c := range r.Client.Jar.Cookies(req.URL) {
+				fmt.Fprintf(OutputWriter(), "%s=%s (jar)\n", c.Name, c.Value)
+			}
 		}
-		return nil, errors.New(errMsg)
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	resp, trace, err := r.do(req)
 	if err != nil {
-		return nil, errors.New("unable to get content, " + err.Error())
+		return nil, trace, r.responseError(err, trace.attempts)
 	}
+	return resp, trace, nil
+}
 
-	result := &RestResponse{string(body), resp}
-	return result, nil
+// responseError -- the error of a request that did not get a response
+func (r *RestClient) responseError(err error, attempts int) error {
+	if IsInterrupt(err) {
+		return err
+	}
+	errMsg := "response returned error, " + err.Error()
+	if attempts > 1 {
+		errMsg = fmt.Sprintf("response returned error after %d attempts, %s", attempts, err.Error())
+	}
+	if r.Debug {
+		fmt.Fprintln(OutputWriter(), errMsg)
+	}
+	return errors.New(errMsg)
 }
 
 func (r *RestClient) DoWithJsonMarshal(method string, authContext Auth, url string, data interface{}) (*RestResponse, error) {
@@ -185,6 +346,10 @@ func (r *RestClient) DoMethodWithBody(method string, authContext Auth, url strin
 		fmt.Fprintf(OutputWriter(), "Request Body:\n%s\n\n", data)
 	}
 
+	if r.configErr != nil {
+		return nil, r.configErr
+	}
+
 	if method == http.MethodGet {
 		fmt.Fprintf(OutputWriter(), "Warning: using a HTTP body with a Get method is not best practice")
 	}
@@ -204,19 +369,19 @@ func (r *RestClient) DoMethodWithBody(method string, authContext Auth, url strin
 
 	addDefaultContentType(req, contentType)
 
+	if err := RunRequestHooks(req); err != nil {
+		return nil, err
+	}
+
 	if r.Debug {
 		fmt.Fprintf(OutputWriter(), "Executing: (%s) %s\n", method, req.URL.String())
 		fmt.Fprintln(OutputWriter(), "Sending Headers:")
 		dumpHeaders(OutputWriter(), req)
 	}
 
-	resp, err := r.Client.Do(req)
+	resp, trace, err := r.do(req)
 	if err != nil {
-		errMsg := "response returned error, " + err.Error()
-		if r.Debug {
-			fmt.Fprintln(OutputWriter(), errMsg)
-		}
-		return nil, errors.New(errMsg)
+		return nil, r.responseError(err, trace.attempts)
 	}
 	defer resp.Body.Close()
 
@@ -225,8 +390,7 @@ func (r *RestClient) DoMethodWithBody(method string, authContext Auth, url strin
 		return nil, errors.New("unable to get content, " + err.Error())
 	}
 
-	result := &RestResponse{string(body), resp}
-	return result, nil
+	return newRestResponse(string(body), resp, trace), nil
 }
 
 // GetX509Pool - Get the X509 pool to use; the system is used by default and
@@ -239,6 +403,13 @@ func GetX509Pool() *x509.CertPool {
 		fmt.Fprintf(ConsoleWriter(), "Warning: SystemCertPool returned error: %s", err.Error())
 		CAPool = x509.NewCertPool()
 	}
+	appendLocalCertificate(CAPool)
+	return CAPool
+}
+
+// appendLocalCertificate -- append the cert.pem file of the init directory or
+// the exe directory to a pool
+func appendLocalCertificate(CAPool *x509.CertPool) {
 	certpath := filepath.Join(GetInitDirectory(), "cert.pem")
 	severCert, err := ioutil.ReadFile(certpath)
 	if err != nil {
@@ -246,11 +417,10 @@ func GetX509Pool() *x509.CertPool {
 		severCert, err = ioutil.ReadFile(certpath)
 		if err != nil {
 			fmt.Fprintln(ConsoleWriter(), "Could not load certificate:", certpath)
-			return CAPool
+			return
 		}
 	}
 	CAPool.AppendCertsFromPEM(severCert)
-	return CAPool
 }
 
 // GetStatus - Get the status return code in the response; if the response is
@@ -271,6 +441,22 @@ func (resp *RestResponse) GetStatusString() string {
 	return "Unknown Status"
 }
 
+// GetAttempts - Get the number of attempts of the request of the response
+func (resp *RestResponse) GetAttempts() int {
+	if resp == nil || resp.attempts < 1 {
+		return 1
+	}
+	return resp.attempts
+}
+
+// GetTiming - Get the timing of the phases of the request of the response
+func (resp *RestResponse) GetTiming() RequestTiming {
+	if resp == nil {
+		return RequestTiming{}
+	}
+	return resp.timing
+}
+
 func (resp *RestResponse) GetCookies() []*http.Cookie {
 	return resp.httpResp.Cookies()
 }
@@ -324,18 +510,6 @@ func PerformHealthCheck(client RestClient, url string) error {
 	return nil
 }
 
-// Warning: this does not work well on windows as system
-// certs may not be available
-// TODO: cleanup relationship with disabling verification as this needs to be done first
-func newTlsWithLocalCertificates(client *http.Client) {
-	var tlsconfig = &tls.Config{RootCAs: GetX509Pool()}
-	if t, ok := client.Transport.(*http.Transport); ok {
-		t.TLSClientConfig = tlsconfig
-	} else {
-		client.Transport = &http.Transport{TLSClientConfig: tlsconfig}
-	}
-}
-
 func addHeaders(req *http.Request, headers []string) error {
 	if len(headers) == 0 {
 		return nil
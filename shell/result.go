@@ -18,11 +18,16 @@ type Result struct {
 	Error            error
 	HttpStatus       int
 	HttpStatusString string
+	Attempts         int // Attempts of the request including retries
 	ContentType      string
 	BodyMap          HistoryMap
 	HeaderMap        HistoryMap
 	CookieMap        HistoryMap
 	AuthMap          HistoryMap
+	TimingMap        HistoryMap
+	Raw              []byte         // Content of binary results
+	Content          *ContentInfo   // Size, hash and type of binary and downloaded content
+	Timing           *RequestTiming // Phases of the request of HTTP results
 	cookies          []*http.Cookie
 	headers          map[string]string
 }
@@ -35,6 +40,7 @@ const (
 	AuthPath       ResultPayloadType = 2
 	CookiePath     ResultPayloadType = 3
 	HeaderPath     ResultPayloadType = 4
+	TimingPath     ResultPayloadType = 5
 	AllPaths       ResultPayloadType = 8
 	AlternatePaths ResultPayloadType = 9 // All paths but default as default is assumed
 )
@@ -71,6 +77,12 @@ func (r *Result) DumpResult(w io.Writer, options ...DisplayOption) {
 
 	if IsStatus(options) || IsHeaders(options) || verbose {
 		fmt.Fprintf(w, "HTTP Status: %s\n", r.HttpStatusString)
+		if r.Attempts > 1 {
+			fmt.Fprintf(w, "Attempts: %d\n", r.Attempts)
+		}
+		if r.Timing != nil && IsCmdVerboseEnabled() {
+			fmt.Fprintf(w, "Timing: %s\n", r.Timing.describe())
+		}
 		verbose = true
 	}
 
@@ -85,7 +97,9 @@ func (r *Result) DumpResult(w io.Writer, options ...DisplayOption) {
 	}
 
 	if IsBody(options) {
-		if IsStringBinary(r.Text) {
+		if r.Content != nil {
+			fmt.Fprint(w, generateResponseLine(r.Content.describe(), verbose))
+		} else if IsStringBinary(r.Text) {
 			fmt.Fprintln(w, "Response contains too many unprintable characters to display")
 		} else {
 			line := r.Text
@@ -112,6 +126,12 @@ func (r *Result) DumpResult(w io.Writer, options ...DisplayOption) {
 	}
 }
 
+// setTiming -- set the timing of the request of the result
+func (r *Result) setTiming(timing RequestTiming) {
+	r.Timing = &timing
+	r.TimingMap = timing.historyMap()
+}
+
 func (r *Result) addCookieMap(resp *RestResponse) error {
 	r.cookies = resp.GetCookies()
 
@@ -181,12 +201,30 @@ func (r *Result) addParsedContentToResult(contentType string, data string) {
 	case "csv":
 		r.BodyMap, _ = NewTextHistoryMap(data)
 	case ResultContentBinary:
-		r.BodyMap, _ = NewTextHistoryMap("")
+		r.setBinaryContent([]byte(data))
 	default:
-		r.BodyMap, _ = NewTextHistoryMap("Unsupported content type returned: " + r.ContentType)
+		if IsStringBinary(data) {
+			r.setBinaryContent([]byte(data))
+		} else {
+			r.BodyMap, _ = NewTextHistoryMap("Unsupported content type returned: " + r.ContentType)
+		}
 	}
 }
 
+// setBinaryContent -- keep the raw bytes of binary content and use the
+// content information as the body map
+func (r *Result) setBinaryContent(data []byte) {
+	info := NewContentInfo(data)
+	r.Raw = data
+	r.setContentInfo(info)
+}
+
+// setContentInfo -- use the content information as the body map
+func (r *Result) setContentInfo(info ContentInfo) {
+	r.Content = &info
+	r.BodyMap = info.historyMap()
+}
+
 // getResultTypeFromResponse -- Get the result type
 //
 //	xml, json, text, html, css, csv, media, unknown
@@ -210,7 +248,7 @@ func getResultTypeFromContentType(contentType string) ResultContentType {
 		return ResultContentText
 	} else if strings.HasPrefix(contentType, "text/html") {
 		return ResultContentHtml
-	} else if strings.HasPrefix(contentType, "application/octet-stream") {
+	} else if isBinaryContentType(contentType) {
 		return ResultContentBinary
 	} else if strings.Contains(contentType, "text/csv") {
 		return ResultContentCsv
@@ -219,6 +257,26 @@ func getResultTypeFromContentType(contentType string) ResultContentType {
 	return ResultContentUnknown
 }
 
+// isBinaryContentType -- true for media types that are not text; parameters
+// of the media type (e.g. charset) are ignored
+func isBinaryContentType(contentType string) bool {
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(contentType, prefix) && !strings.HasSuffix(contentType, "+xml") {
+			return true
+		}
+	}
+	switch contentType {
+	case "application/octet-stream", "application/pdf", "application/zip", "application/gzip",
+		"application/x-gzip", "application/x-tar", "application/x-7z-compressed":
+		return true
+	}
+	return false
+}
+
 func generateResponseLine(line string, verbose bool) string {
 	responseLabel := ""
 	if verbose {
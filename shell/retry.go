@@ -0,0 +1,244 @@
+// Retries
+//
+// Requests that fail with a network error or a transient status are retried
+// with the retry policy of the client. The --retry option (or the
+// .config.restshell.retry variable) sets the number of retries, --retry-on
+// (.config.restshell.retry.on) the status codes and "network" for network
+// errors and --retry-delay (.config.restshell.retry.delay) the delay of the
+// first retry. The delay doubles with each retry with a random jitter up to
+// 30 seconds; a Retry-After header of the response replaces the delay.
+// Benchmarks only retry with the --retry option so retries do not change
+// their measurements silently.
+
+package shell
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variables of the retry policy
+var (
+	RetryVariable      = ".config.restshell.retry"
+	RetryOnVariable    = ".config.restshell.retry.on"
+	RetryDelayVariable = ".config.restshell.retry.delay"
+)
+
+// Defaults of the retry policy
+const (
+	DefaultRetryOn       = "network,429,502,503,504"
+	DefaultRetryDelay    = 500 * time.Millisecond
+	DefaultRetryMaxDelay = 30 * time.Second
+	RetryOnNetwork       = "network"
+)
+
+// RetryPolicy -- when and how often a request is retried
+type RetryPolicy struct {
+	MaxAttempts   int   // Attempts of a request including the first (0 or 1 for no retries)
+	StatusCodes   []int // Status codes retried
+	NetworkErrors bool  // Retry network errors
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+}
+
+// NewRetryPolicy -- a policy retrying a number of times on a comma separated
+// list of status codes and "network"
+func NewRetryPolicy(retries int, retryOn string, delay time.Duration) (RetryPolicy, error) {
+	policy := RetryPolicy{MaxAttempts: retries + 1, BaseDelay: delay, MaxDelay: DefaultRetryMaxDelay}
+	for _, value := range strings.Split(retryOn, ",") {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		} else if strings.EqualFold(value, RetryOnNetwork) {
+			policy.NetworkErrors = true
+		} else if code, err := strconv.Atoi(value); err == nil && code >= 100 && code <= 599 {
+			policy.StatusCodes = append(policy.StatusCodes, code)
+		} else {
+			return RetryPolicy{}, fmt.Errorf("invalid retry condition: %s", value)
+		}
+	}
+	if retries < 0 {
+		return RetryPolicy{}, errors.New("retries cannot be negative")
+	}
+	return policy, nil
+}
+
+// GetCmdRetryPolicy -- the retry policy of the command options and the retry
+// variables; benchmarks only use the variables with the --retry option
+func GetCmdRetryPolicy() (RetryPolicy, error) {
+	return globalOptions.GetRetryPolicy()
+}
+
+// GetRetryPolicy -- the retry policy of the options and the retry variables
+func (o *StandardOptions) GetRetryPolicy() (RetryPolicy, error) {
+	benchmark := o.iterationOption != nil
+	retries := 0
+	if o.retryOption != nil && *o.retryOption != OptionDefaultRetry {
+		retries = *o.retryOption
+	} else if value := GetGlobalString(RetryVariable); len(value) > 0 && !benchmark {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("invalid %s: %s", RetryVariable, value)
+		}
+		retries = n
+	}
+
+	retryOn := getOptionOrVariable(o.retryOnOption, RetryOnVariable)
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryOn
+	}
+
+	delay := DefaultRetryDelay
+	if value := getOptionOrVariable(o.retryDelayOption, RetryDelayVariable); len(value) > 0 {
+		d, err := ParseDuration(value, "ms")
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("invalid retry delay: %s", value)
+		}
+		delay = d
+	}
+	return NewRetryPolicy(retries, retryOn, delay)
+}
+
+// isRetryStatus -- true when the policy retries a status code
+func (p RetryPolicy) isRetryStatus(status int) bool {
+	for _, code := range p.StatusCodes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryError -- true when the policy retries the error of a request;
+// certificate errors are not transient
+func (p RetryPolicy) isRetryError(err error) bool {
+	if !p.NetworkErrors {
+		return false
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	return !errors.As(err, &unknownAuthority) && !errors.As(err, &invalid) && !errors.As(err, &hostname)
+}
+
+// delay -- the delay before a retry: the Retry-After of a response or an
+// exponential backoff with jitter; no delay without a base delay
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0 // Retry immediately
+	}
+
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if after > maxDelay {
+				return maxDelay
+			}
+			return after
+		}
+	}
+
+	// The backoff doubles for each attempt; a shift overflowing the duration
+	// is limited to the maximum delay as well
+	shift := uint(attempt - 1)
+	backoff := p.BaseDelay << shift
+	if shift >= 63 || backoff>>shift != p.BaseDelay || backoff > maxDelay {
+		backoff = maxDelay
+	}
+	// Full jitter between half and all of the backoff
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// parseRetryAfter -- parse a Retry-After header in seconds or as a date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// do -- send a request retrying transient failures with the retry policy of
+// the client; returns the response and the trace of the attempts
+func (r *RestClient) do(req *http.Request) (*http.Response, *requestTrace, error) {
+	trace := &requestTrace{}
+	ctx := req.Context()
+	header := req.Header.Clone() // The client adds the cookies of the jar to the headers
+	for attempt := 1; ; attempt++ {
+		resp, err := r.Client.Do(req.WithContext(trace.attempt(ctx)))
+		if attempt >= r.Retry.MaxAttempts {
+			return resp, trace, err
+		}
+
+		reason := ""
+		if err != nil && r.Retry.isRetryError(err) {
+			reason = err.Error()
+		} else if err == nil && r.Retry.isRetryStatus(resp.StatusCode) {
+			reason = resp.Status
+		} else {
+			return resp, trace, err
+		}
+
+		// The request is sent again with the original headers and a new body
+		next := req.Clone(ctx)
+		next.Header = header.Clone()
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, trace, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, trace, err
+			}
+			next.Body = body
+		}
+
+		delay := r.Retry.delay(attempt, resp)
+		if r.Debug || r.Verbose {
+			fmt.Fprintf(OutputWriter(), "Retrying in %s after %s (attempt %d of %d)\n",
+				FormatMsTime(float64(delay)/float64(time.Millisecond)), reason, attempt+1, r.Retry.MaxAttempts)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if !waitForRetry(delay) {
+			return nil, trace, NewInterruptError()
+		}
+		req = next
+	}
+}
+
+// waitForRetry -- wait before a retry; returns false when interrupted (Ctrl-C)
+func waitForRetry(delay time.Duration) bool {
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, os.Interrupt)
+	defer signal.Stop(sigchan)
+
+	select {
+	case <-sigchan:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
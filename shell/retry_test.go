@@ -0,0 +1,175 @@
+package shell
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRetryClient(t *testing.T, retries int, retryOn string) RestClient {
+	t.Helper()
+	policy, err := NewRetryPolicy(retries, retryOn, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	client := NewRestClient()
+	client.Retry = policy
+	return client
+}
+
+// newFlakyServer -- a server failing with a status until a number of requests
+// were received; successful responses echo the request body
+func newFlakyServer(t *testing.T, failures int, status int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if requests <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryTransientStatus(t *testing.T) {
+	server, requests := newFlakyServer(t, 2, http.StatusServiceUnavailable)
+	client := newTestRetryClient(t, 3, DefaultRetryOn)
+
+	resp, err := client.DoWithJson(http.MethodPost, nil, server.URL, `{"a":1}`)
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	if resp.GetStatus() != http.StatusOK || resp.Text != `{"a":1}` || resp.GetAttempts() != 3 || *requests != 3 {
+		t.Errorf("expected the body to be sent again until success: %d %q after %d attempts",
+			resp.GetStatus(), resp.Text, resp.GetAttempts())
+	}
+
+	PushResponse(resp, nil)
+	if result, _ := PeekResult(0); result.Attempts != 3 {
+		t.Errorf("expected the attempts in the result but got %d", result.Attempts)
+	}
+}
+
+func TestRetryStopsAfterMaxAttempts(t *testing.T) {
+	server, requests := newFlakyServer(t, 5, http.StatusBadGateway)
+	client := newTestRetryClient(t, 2, "502")
+
+	resp, err := client.DoMethod(http.MethodGet, nil, server.URL)
+	if err != nil || resp.GetStatus() != http.StatusBadGateway || resp.GetAttempts() != 3 || *requests != 3 {
+		t.Errorf("expected the last failure after 3 attempts: %v %d", err, *requests)
+	}
+
+	server, requests = newFlakyServer(t, 1, http.StatusInternalServerError)
+	resp, _ = client.DoMethod(http.MethodGet, nil, server.URL)
+	if resp.GetStatus() != http.StatusInternalServerError || *requests != 1 {
+		t.Errorf("expected no retry of a status that is not retried")
+	}
+}
+
+func TestRetryNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := newTestRetryClient(t, 1, "network")
+	_, err := client.DoMethod(http.MethodGet, nil, url)
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("expected the network error after 2 attempts but got %v", err)
+	}
+}
+
+func TestRetryPolicyOptionsAndVariables(t *testing.T) {
+	if _, err := NewRetryPolicy(1, "503,teapot", time.Second); err == nil {
+		t.Errorf("expected an error for an invalid retry condition")
+	}
+
+	SetGlobal(RetryVariable, "2")
+	defer RemoveGlobal(RetryVariable)
+
+	options := StandardOptions{}
+	if policy, _ := options.GetRetryPolicy(); policy.MaxAttempts != 3 || !policy.NetworkErrors || !policy.isRetryStatus(503) {
+		t.Errorf("expected the retry variable with the default conditions: %v", policy)
+	}
+
+	set := NewCmdSet()
+	saved := globalOptions
+	defer func() { globalOptions = saved }()
+	ClearCmdOptions()
+	AddCommonCmdOptions(set, CmdBenchmarks, CmdRestclient)
+	CmdParse(set, []string{"bmget"})
+	if policy, _ := GetCmdRetryPolicy(); policy.MaxAttempts != 1 {
+		t.Errorf("expected benchmarks to ignore the retry variable: %v", policy)
+	}
+	CmdParse(set, []string{"bmget", "--retry", "1", "--retry-on", "500"})
+	if policy, _ := GetCmdRetryPolicy(); policy.MaxAttempts != 2 || policy.NetworkErrors || !policy.isRetryStatus(500) {
+		t.Errorf("expected benchmarks to retry with the retry option: %v", policy)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max = max * time.Millisecond
+		if d := policy.delay(attempt+1, nil); d < max/2 || d > max {
+			t.Errorf("delay of attempt %d out of range: %s", attempt+1, d)
+		}
+	}
+
+	for _, attempt := range []int{64, 65, 200} {
+		if d := policy.delay(attempt, nil); d < 500*time.Millisecond || d > time.Second {
+			t.Errorf("expected the maximum delay for an overflowing attempt %d but got %s", attempt, d)
+		}
+	}
+	large := RetryPolicy{BaseDelay: 3 * time.Hour, MaxDelay: 100 * time.Hour}
+	if d := large.delay(40, nil); d < 50*time.Hour || d > 100*time.Hour {
+		t.Errorf("expected the maximum delay for an overflowing backoff but got %s", d)
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "3")
+	if d := (RetryPolicy{MaxDelay: time.Second}).delay(1, resp); d != 0 {
+		t.Errorf("expected no delay without a base delay but got %s", d)
+	}
+	if d := policy.delay(1, resp); d != time.Second {
+		t.Errorf("expected the Retry-After limited to the maximum delay but got %s", d)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	if d := policy.delay(1, resp); d != 0 {
+		t.Errorf("expected no delay for a past Retry-After date but got %s", d)
+	}
+}
+
+func TestRetryWithCookieJar(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "a", Value: "1", Path: "/"})
+			return
+		}
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Cookie") + "|" + r.Header.Get("X-Test")))
+	}))
+	defer server.Close()
+
+	client := newTestRetryClient(t, 3, DefaultRetryOn)
+	client.Client.Jar = NewCookieJar()
+	client.Headers = []string{"X-Test=keep"}
+	client.DoMethod(http.MethodGet, nil, server.URL+"/login")
+
+	resp, err := client.DoMethod(http.MethodGet, nil, server.URL+"/books")
+	if err != nil || resp.GetAttempts() != 3 || resp.Text != "a=1|keep" {
+		t.Errorf("expected the cookie and headers once after retries but got %q (%v)", resp.Text, err)
+	}
+}
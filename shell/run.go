@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -22,8 +23,16 @@ type RunCommand struct {
 	list            *bool
 	ifCondition     *string
 	stepOption      *bool
+	breakOption     *string
 	iterationOption *int
 	execOption      *bool
+	traceOption     *bool
+	checkOption     *bool
+	resultOption    *string
+	parallelOption  *bool
+	failFastOption  *bool
+	bufferOption    *bool
+	cancel          context.CancelFunc
 	// Note: nesting makes count only valid from end of execute and calling CommandCount() immediately
 	count int
 }
@@ -36,13 +45,20 @@ func NewRunCommand() *RunCommand {
 }
 
 func (cmd *RunCommand) AddOptions(set CmdSet) {
-	set.SetParameters("scripts...")
-	cmd.ifCondition = set.StringLong("cond", 0, "", "run script if specified variable is not empty or matches optional value (k[=v])")
-	cmd.list = set.BoolLong("list", 0, "List the contexts of script file")
+	set.SetParameters("scripts... [-- args...]")
+	cmd.ifCondition = set.StringLong("cond", 0, "", "run script if specified variable is not empty or matches optional value (k[=v]) or the expression is true (=expr)")
+	cmd.list = set.BoolLong("list", 0, "List the contexts of script file (or the scripts in the search path without a file)")
 	cmd.header = set.BoolLong("header", 0, "Display header of script file (Leading REM commands)")
 	cmd.stepOption = set.BoolLong("step", 0, "Single step through script")
+	cmd.breakOption = set.StringLong("break", 'b', "", "Stop the debugger at breakpoints (file:line or command, comma separated)", "breakpoints")
 	cmd.iterationOption = set.IntLong("iterations", 'i', 1, "run the script iteration number of times")
 	cmd.execOption = set.BoolLong("exec", 0, "Execute quoted parameters as script commands")
+	cmd.checkOption = set.BoolLong("check", 0, "Validate the scripts and nested scripts without executing them")
+	cmd.traceOption = set.BoolLong("trace", 0, "Display the script call stack when a script is aborted")
+	cmd.resultOption = set.StringLong("result", 'r', DefaultProcResultVariable, "Variable to receive the value returned by a script", "var")
+	cmd.parallelOption = set.BoolLong("parallel", 0, "Run the scripts (or commands with --exec) in parallel with a copy of the shell state")
+	cmd.failFastOption = set.BoolLong("fail-fast", 0, "Stop the parallel scripts when a script fails")
+	cmd.bufferOption = set.BoolLong("buffer", 0, "Display the output of a parallel script when it completes")
 	AddCommonCmdOptions(set, CmdDebug, CmdVerbose, CmdSilent)
 }
 
@@ -79,19 +95,19 @@ func ValidateScriptExists(file string) (string, error) {
 	return file, nil
 }
 
-func (cmd *RunCommand) executeFile(file string, runSilent bool) (count int, elapsed time.Duration, result error) {
+func (cmd *RunCommand) executeFile(file string, scriptArgs []string, runSilent bool) (count int, elapsed time.Duration, value *string, result error) {
 	count = 0
 	elapsed = 0
 
 	var path = ""
 	{
-		scriptFile, err := ValidateScriptExists(file)
+		scriptFile, err := FindScript(file)
 		if err != nil {
 			if runSilent {
 				// We do not care about file existance issues
-				return 0, 0, nil
+				return 0, 0, nil, nil
 			}
-			return 0, 0, err
+			return 0, 0, nil, newScriptError(err)
 		}
 
 		if abspath, err := filepath.Abs(scriptFile); err == nil {
@@ -105,7 +121,7 @@ func (cmd *RunCommand) executeFile(file string, runSilent bool) (count int, elap
 	}
 	h, err := os.Open(file)
 	if err != nil {
-		return 0, 0, errors.New("Failed to read script file: " + err.Error())
+		return 0, 0, nil, newScriptError(errors.New("Failed to read script file: " + err.Error()))
 	}
 
 	curdir, err := os.Getwd()
@@ -137,19 +153,20 @@ func (cmd *RunCommand) executeFile(file string, runSilent bool) (count int, elap
 
 	if *cmd.header || *cmd.list {
 		listfile(h, *cmd.header)
-		return 0, 0, nil
+		return 0, 0, nil, nil
 	}
 
+	defer PushScope("run:" + filepath.Base(file))()
+	setScriptArguments(file, scriptArgs)
+	cmd.addBreakpoints()
+
 	startTime := time.Now()
-	commands, success := CommandProcessor("", h, *cmd.stepOption, true)
+	commands, value, err := processScript(h, file, *cmd.stepOption)
 	elapsed = time.Since(startTime)
-	if !success {
-		return commands, elapsed, errors.New("Command processor failed")
-	}
-	return commands, elapsed, nil
+	return commands, elapsed, value, err
 }
 
-func (cmd *RunCommand) executeStream(r io.Reader, runSilent bool) (count int, elapsed time.Duration, result error) {
+func (cmd *RunCommand) executeStream(r io.Reader, scriptArgs []string, runSilent bool) (count int, elapsed time.Duration, value *string, result error) {
 	count = 0
 	elapsed = 0
 
@@ -163,16 +180,29 @@ func (cmd *RunCommand) executeStream(r io.Reader, runSilent bool) (count int, el
 
 	if *cmd.header || *cmd.list {
 		listfile(r, *cmd.header)
-		return 0, 0, nil
+		return 0, 0, nil, nil
 	}
 
+	defer PushScope("run:exec")()
+	setScriptArguments("exec", scriptArgs)
+	cmd.addBreakpoints()
+
 	startTime := time.Now()
-	commands, success := CommandProcessor("", r, *cmd.stepOption, true)
+	commands, value, err := processScript(r, "", *cmd.stepOption)
 	elapsed = time.Since(startTime)
-	if !success {
-		return commands, elapsed, errors.New("Command processor failed")
+	return commands, elapsed, value, err
+}
+
+// addBreakpoints -- add the breakpoints of the --break option when debugging interactively
+func (cmd *RunCommand) addBreakpoints() {
+	if len(*cmd.breakOption) == 0 || !isInteractive() {
+		return
+	}
+	for _, v := range strings.Split(*cmd.breakOption, ",") {
+		if err := AddBreakpoint(v); err != nil {
+			fmt.Fprintf(ErrorWriter(), "Warning: %s: %s\n", err.Error(), v)
+		}
 	}
-	return commands, elapsed, nil
 }
 
 func (cmd *RunCommand) Execute(args []string) error {
@@ -181,19 +211,43 @@ func (cmd *RunCommand) Execute(args []string) error {
 	cmd.interrupted = false
 	iterations := *cmd.iterationOption
 
+	args, scriptArgs := splitScriptArguments(args)
+	if len(args) == 0 && *cmd.list {
+		return listScriptPath()
+	}
 	if len(args) == 0 {
 		return errors.New("specify at least one file to run")
 	}
 
+	if *cmd.checkOption {
+		return checkScripts(args, *cmd.execOption)
+	}
+
 	if cmd.running > 3 {
 		return errors.New("too many nested scripts script")
 	}
 
-	if !isConditionMet(*cmd.ifCondition) {
+	resultVar := *cmd.resultOption
+	if !IsValidKey(resultVar) {
+		return ErrInvalidKey
+	}
+
+	if met, err := isConditionMet(*cmd.ifCondition); err != nil {
+		return err
+	} else if !met {
 		fmt.Fprintf(OutputWriter(), "Run command aborting; missing required condition: %s.\n", *cmd.ifCondition)
 		return nil
 	}
 
+	if *cmd.traceOption && !traceEnabled {
+		traceEnabled = true
+		defer func() { traceEnabled = false }()
+	}
+
+	if *cmd.parallelOption {
+		return cmd.executeParallelScripts(args, scriptArgs)
+	}
+
 	runSilent := IsCmdSilentEnabled() || *cmd.list
 	i := iterations
 	var result error
@@ -205,7 +259,7 @@ func (cmd *RunCommand) Execute(args []string) error {
 		if execLocal {
 			str := strings.Join(args, "\n")
 			r := strings.NewReader(str)
-			count, elapsed, err := cmd.executeStream(r, runSilent)
+			count, elapsed, value, err := cmd.executeStream(r, scriptArgs, runSilent)
 			commands = commands + count
 			duration = duration + elapsed
 			if err != nil {
@@ -213,11 +267,20 @@ func (cmd *RunCommand) Execute(args []string) error {
 				resultMsg = err.Error()
 				break
 			}
+			if value != nil {
+				SetGlobal(resultVar, *value)
+			}
 		} else {
 			for _, fileName := range args {
-				count, elapsed, err := cmd.executeFile(fileName, runSilent)
+				count, elapsed, value, err := cmd.executeFile(fileName, scriptArgs, runSilent)
 				commands = commands + count
 				duration = duration + elapsed
+				if isExitRequest(err) {
+					return err
+				}
+				if value != nil {
+					SetGlobal(resultVar, *value)
+				}
 				if err != nil {
 					if len(args) > 1 {
 						fmt.Fprintf(ErrorWriter(), "Aborting due to errors in script: %s\n", fileName)
@@ -230,6 +293,9 @@ func (cmd *RunCommand) Execute(args []string) error {
 	}
 
 	cmd.count = commands
+	if isExitRequest(result) {
+		return result
+	}
 	if result == nil && LastError != 0 {
 		resultMsg = "Last command returned an error"
 	}
@@ -246,6 +312,38 @@ func (cmd *RunCommand) Execute(args []string) error {
 	return nil
 }
 
+// executeParallelScripts -- run each script (or command with --exec) as a
+// parallel branch
+func (cmd *RunCommand) executeParallelScripts(args []string, scriptArgs []string) error {
+	branches := make([]parallelBranch, 0, len(args))
+	for _, arg := range args {
+		if *cmd.execOption {
+			branches = append(branches, parallelBranch{name: strconv.Itoa(len(branches) + 1), script: arg + "\n"})
+			continue
+		}
+
+		// Arguments were substituted by this RUN so the branch does not substitute
+		script := "$RUN " + quoteParameter(arg)
+		if len(scriptArgs) > 0 {
+			script += " --"
+			for _, scriptArg := range scriptArgs {
+				script += " " + quoteParameter(scriptArg)
+			}
+		}
+		branches = append(branches, parallelBranch{name: filepath.Base(arg), script: script + "\n"})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd.cancel = cancel
+	defer func() {
+		cancel()
+		cmd.cancel = nil
+	}()
+
+	options := parallelOptions{failFast: *cmd.failFastOption, buffer: *cmd.bufferOption}
+	return executeParallel(ctx, branches, options)
+}
+
 func (cmd *RunCommand) DoNotCount() bool {
 	return true
 }
@@ -260,6 +358,9 @@ func (cmd *RunCommand) CommandCount() int {
 
 func (cmd *RunCommand) Abort() {
 	cmd.interrupted = true
+	if cmd.cancel != nil {
+		cmd.cancel()
+	}
 }
 
 func getDurationString(duration time.Duration) string {
@@ -270,10 +371,19 @@ func getDurationString(duration time.Duration) string {
 	}
 }
 
-func isConditionMet(variable string) bool {
+// isConditionMet -- test a variable (k[=v]) or an expression (=expr)
+func isConditionMet(variable string) (bool, error) {
 	variable = strings.TrimSpace(variable)
 	if len(variable) <= 0 {
-		return true
+		return true, nil
+	}
+
+	if strings.HasPrefix(variable, "=") {
+		value, err := EvaluateExpression(variable[1:])
+		if err != nil {
+			return false, fmt.Errorf("invalid condition: %s", err.Error())
+		}
+		return IsExpressionTrue(value), nil
 	}
 
 	parts := strings.Split(variable, "=")
@@ -283,18 +393,88 @@ func isConditionMet(variable string) bool {
 	if str, ok := value.(string); ok {
 		str = strings.TrimSpace(str)
 		if len(parts) > 1 {
-			return str == strings.TrimSpace(parts[1])
+			return str == strings.TrimSpace(parts[1]), nil
 		} else {
 			if len(str) > 0 {
-				return true
+				return true, nil
 			}
 		}
 	} else if value != nil {
-		return true
+		return true, nil
 	} else if len(parts) > 1 && len(strings.TrimSpace(parts[1])) == 0 {
-		return true
+		return true, nil
+	}
+	return false, nil
+}
+
+// checkScripts -- validate the scripts and report the problems found
+func checkScripts(scripts []string, exec bool) error {
+	problems := lintScripts(scripts, exec)
+	for _, problem := range problems {
+		fmt.Fprintln(OutputWriter(), problem.String())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+	if !IsCmdSilentEnabled() {
+		fmt.Fprintln(OutputWriter(), "No problems found")
+	}
+	return nil
+}
+
+// splitScriptArguments -- split the arguments at "--" into the scripts to
+// run and the arguments passed to the scripts
+func splitScriptArguments(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
 	}
-	return false
+	return args, []string{}
+}
+
+// setScriptArguments -- set the local variables for the arguments of a
+// script. The script name is %%0%%, positional arguments are %%1%%...%%n%%
+// with the count in %%argc%%, and named arguments (name=value) set the named
+// variable. Positional arguments of a calling script are hidden.
+func setScriptArguments(script string, args []string) {
+	positional := make([]string, 0)
+	named := make(map[string]string)
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok && IsValidKey(k) && !isPositionalKey(k) {
+			named[k] = v
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	EnumerateGlobals(func(k string, v interface{}) {
+		HideLocal(k)
+	}, func(k string, v interface{}) bool {
+		return isPositionalKey(k)
+	})
+
+	SetLocal("0", script)
+	SetLocal("argc", strconv.Itoa(len(positional)))
+	for i, arg := range positional {
+		SetLocal(strconv.Itoa(i+1), arg)
+	}
+	for k, v := range named {
+		SetLocal(k, v)
+	}
+}
+
+// isPositionalKey -- true for the variable names of positional arguments
+func isPositionalKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func listfile(reader io.Reader, onlyHeader bool) {
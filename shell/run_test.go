@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -11,3 +13,43 @@ func TestRunInterfaceDefinition(t *testing.T) {
 		t.Errorf("Run command was not implementing Trackable interface")
 	}
 }
+
+func TestRunScriptArguments(t *testing.T) {
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "inner.rshell")
+	outer := filepath.Join(dir, "outer.rshell")
+	os.WriteFile(inner, []byte("record inner %%argc%% %%1%% %%2%% %%name%%\n"), 0644)
+	os.WriteFile(outer, []byte("record outer %%argc%% %%1%% %%2%% %%name%%\nrun -s inner -- x\nrecord outer %%argc%% %%1%% %%2%% %%name%%\n"), 0644)
+
+	SetGlobal("name", "global")
+	script := "run -s " + outer + " -- a name=b c\nrecord %%name%%\n"
+	verifyRecorded(t, runTestScript(script),
+		"outer 2 a c b",
+		"inner 1 x %%2%% b",
+		"outer 2 a c b",
+		"global")
+	if GetGlobal("1") != nil || GetGlobal("argc") != nil {
+		t.Errorf("expected script arguments to be removed after run")
+	}
+}
+
+func TestRunExecScriptArguments(t *testing.T) {
+	SetGlobal("name", "global")
+	script := "$run -s --exec \"record %%argc%% %%1%% %%name%%\" -- a name=b\nrecord %%name%% %%argc%%\n"
+	verifyRecorded(t, runTestScript(script), "1 a b", "global %%argc%%")
+	if GetGlobal("1") != nil || GetGlobal("0") != nil {
+		t.Errorf("expected the arguments of --exec to be removed after run")
+	}
+}
+
+func TestSplitScriptArguments(t *testing.T) {
+	scripts, args := splitScriptArguments([]string{"a", "b", "--", "c", "--"})
+	if len(scripts) != 2 || len(args) != 2 || args[1] != "--" {
+		t.Errorf("unexpected split: %v %v", scripts, args)
+	}
+
+	scripts, args = splitScriptArguments([]string{"a"})
+	if len(scripts) != 1 || len(args) != 0 {
+		t.Errorf("unexpected split: %v %v", scripts, args)
+	}
+}
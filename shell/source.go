@@ -0,0 +1,146 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scriptLine -- a raw line read by the command processor and its location
+type scriptLine struct {
+	Text string
+	File string
+	Line int
+}
+
+// Location -- the file:line of the line or empty if not read from a file
+func (l scriptLine) Location() string {
+	if len(l.File) == 0 {
+		return ""
+	}
+	return l.File + ":" + strconv.Itoa(l.Line)
+}
+
+// locationOrLine -- the location of the line or its line number
+func (l scriptLine) locationOrLine() string {
+	if location := l.Location(); len(location) > 0 {
+		return location
+	}
+	return "line " + strconv.Itoa(l.Line)
+}
+
+// heredocPattern -- a line ending with a heredoc marker (e.g. <<END)
+var heredocPattern = regexp.MustCompile(`\s<<([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// continuationPattern -- a line ending with a backslash preceded by
+// whitespace; paths ending with a backslash (C:\temp\) do not continue
+var continuationPattern = regexp.MustCompile(`(^|[ \t])\\[ \t]*$`)
+
+// scriptReader -- reads the lines of a script for the command processor.
+// Lines ending with " \" continue on the next line and a line ending
+// with <<WORD is followed by a body terminated by a line containing WORD;
+// the body is passed to the command as a single quoted parameter.
+type scriptReader struct {
+	scanner *bufio.Scanner
+	file    string
+	line    int
+	err     error
+	prompt  func() // Called before reading a continuation or body line
+}
+
+func newScriptReader(reader io.Reader, file string) *scriptReader {
+	return &scriptReader{scanner: bufio.NewScanner(reader), file: file}
+}
+
+// ReadLine -- read the next line; returns false at the end of the input
+func (r *scriptReader) ReadLine() (scriptLine, bool) {
+	text, ok := r.readPhysicalLine()
+	if !ok {
+		return scriptLine{}, false
+	}
+	result := scriptLine{Text: text, File: r.file, Line: r.line}
+
+	if strings.HasPrefix(strings.TrimSpace(text), "#") {
+		return result, true
+	}
+
+	for isContinuedLine(result.Text) {
+		next, ok := r.readContinuationLine()
+		if !ok {
+			break
+		}
+		text := strings.TrimSuffix(strings.TrimRight(result.Text, " \t"), "\\")
+		result.Text = strings.TrimRight(text, " \t") + " " + strings.TrimSpace(next)
+	}
+	result.Text = strings.TrimRight(result.Text, " \t")
+
+	if match := heredocPattern.FindStringSubmatchIndex(result.Text); match != nil {
+		marker := result.Text[match[2]:match[3]]
+		body, err := r.readHeredoc(marker)
+		if err != nil {
+			r.err = fmt.Errorf("%s: %s", result.locationOrLine(), err.Error())
+			return scriptLine{}, false
+		}
+		result.Text = result.Text[:match[0]] + " " + quoteParameter(body)
+	}
+	return result, true
+}
+
+// isContinuedLine -- true when a line continues on the next line; the
+// argument of SHELL may end with a backslash
+func isContinuedLine(text string) bool {
+	if !continuationPattern.MatchString(text) {
+		return false
+	}
+	fields := strings.Fields(text)
+	return !(len(fields) > 1 && strings.EqualFold(fields[0], "SHELL"))
+}
+
+// Err -- the error that stopped the reader if not the end of input
+func (r *scriptReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.scanner.Err()
+}
+
+func (r *scriptReader) readPhysicalLine() (string, bool) {
+	if !r.scanner.Scan() {
+		return "", false
+	}
+	r.line++
+	return r.scanner.Text(), true
+}
+
+func (r *scriptReader) readContinuationLine() (string, bool) {
+	if r.prompt != nil {
+		r.prompt()
+	}
+	return r.readPhysicalLine()
+}
+
+// readHeredoc -- read the lines of a heredoc body until the marker line
+func (r *scriptReader) readHeredoc(marker string) (string, error) {
+	lines := make([]string, 0)
+	for {
+		text, ok := r.readContinuationLine()
+		if !ok {
+			return "", fmt.Errorf("heredoc is missing the terminator %s", marker)
+		}
+		if strings.TrimSpace(text) == marker {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, text)
+	}
+}
+
+// quoteParameter -- quote a value so the command line parser returns it as
+// a single parameter
+func quoteParameter(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	return "\"" + value + "\""
+}
@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScriptReaderJoinsContinuationLines(t *testing.T) {
+	reader := newScriptReader(strings.NewReader("record one \\\n   two \\\n  three\nrecord four\n"), "test.rshell")
+	first, _ := reader.ReadLine()
+	second, _ := reader.ReadLine()
+	if first.Text != "record one two three" || first.Line != 1 {
+		t.Errorf("unexpected continued line: %q at %d", first.Text, first.Line)
+	}
+	if second.Text != "record four" || second.Line != 4 {
+		t.Errorf("unexpected line after continuation: %q at %d", second.Text, second.Line)
+	}
+}
+
+func TestScriptReaderKeepsTrailingBackslashPaths(t *testing.T) {
+	script := "cd C:\\temp\\\nrecord one\nSHELL dir \\\nrecord two\nrecord a\\b \\\n c\n"
+	reader := newScriptReader(strings.NewReader(script), "")
+	for _, expected := range []string{`cd C:\temp\`, "record one", `SHELL dir \`, "record two", `record a\b c`} {
+		if line, ok := reader.ReadLine(); !ok || line.Text != expected {
+			t.Errorf("expected %q but got %q", expected, line.Text)
+		}
+	}
+}
+
+func TestScriptReaderHeredoc(t *testing.T) {
+	reader := newScriptReader(strings.NewReader("post /orders --json <<END\n{\n  \"id\": \"a\\b\"\n}\n  END\nrecord next\n"), "")
+	line, _ := reader.ReadLine()
+	expected := `post /orders --json "{` + "\n" + `  \"id\": \"a\\b\"` + "\n" + `}"`
+	if line.Text != expected {
+		t.Errorf("unexpected heredoc line: %q", line.Text)
+	}
+	if tokens := LineParse(line.Text); len(tokens) != 4 || tokens[3] != "{\n  \"id\": \"a\\b\"\n}" {
+		t.Errorf("unexpected heredoc parameter: %q", tokens)
+	}
+	if next, _ := reader.ReadLine(); next.Text != "record next" || next.Line != 6 {
+		t.Errorf("unexpected line after heredoc: %q at %d", next.Text, next.Line)
+	}
+}
+
+func TestScriptReaderUnterminatedHeredoc(t *testing.T) {
+	reader := newScriptReader(strings.NewReader("post /orders <<END\n{}\n"), "test.rshell")
+	if _, ok := reader.ReadLine(); ok {
+		t.Errorf("expected the reader to stop")
+	}
+	if reader.Err() == nil || reader.Err().Error() != "test.rshell:1: heredoc is missing the terminator END" {
+		t.Errorf("unexpected error: %v", reader.Err())
+	}
+}
+
+func TestHeredocSubstitution(t *testing.T) {
+	SetGlobal("heredoc", "value")
+	script := `
+record <<BODY
+  "%%heredoc%%" \ text
+BODY
+$record <<BODY
+  %%heredoc%%
+BODY
+# comment \
+record done
+`
+	verifyRecorded(t, runTestScript(script), `  "value" \ text`, "  %%heredoc%%", "done")
+}
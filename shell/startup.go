@@ -2,12 +2,14 @@ package shell
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
 	"github.com/pborman/getopt/v2"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // Default settings for startup
@@ -51,7 +53,7 @@ func RunShell(options StartupOptions) (exitCode int) {
 				buf := make([]byte, 1<<16)
 				length := runtime.Stack(buf, true)
 				fmt.Fprintln(ConsoleWriter(), string(buf[:length]))
-				exitCode = 100 // Return 100 for exception
+				exitCode = ExitPanic
 			}
 		}()
 	}
@@ -78,10 +80,22 @@ func RunShell(options StartupOptions) (exitCode int) {
 		return 0
 	}
 
-	runInitScripts(options)
+	// A branch of a parallel execution starts with a copy of the parent state
+	if file := os.Getenv(ParallelBranchVariable); len(file) > 0 {
+		return runBranch(file)
+	}
+
+	// EXIT in an init script ends the shell with the exit code
+	if err := runInitScripts(options); err != nil {
+		return GetExitCode(err)
+	}
 
 	if len(ProgramArgs) == 0 {
-		cnt, success := CommandProcessor(">> ", os.Stdin, false, false)
+		var input io.Reader = os.Stdin
+		if terminal.IsTerminal(int(os.Stdin.Fd())) {
+			input = newTerminalLineEditor(getHistoryFileName())
+		}
+		cnt, success := CommandProcessor(">> ", input, false, false)
 		if !success {
 			fmt.Println("Did not return success")
 		} else {
@@ -95,15 +109,19 @@ func RunShell(options StartupOptions) (exitCode int) {
 	return exitCode
 }
 
-func runInitScripts(options StartupOptions) {
+// runInitScripts -- run the init scripts and return the EXIT request of the
+// shell ending the scripts
+func runInitScripts(options StartupOptions) error {
 	scriptFile := DefaultInitFileName
-	runInitScript(scriptFile, options.DebugInit)
+	if err := runInitScript(scriptFile, options.DebugInit); err != nil {
+		return err
+	}
 
 	scriptFile = DefaultInitFileName + DefaultInitFileExt
-	runInitScript(scriptFile, options.DebugInit)
+	return runInitScript(scriptFile, options.DebugInit)
 }
 
-func runInitScript(scriptFile string, debug bool) {
+func runInitScript(scriptFile string, debug bool) error {
 	if _, err := ValidateScriptExists(scriptFile); err != nil {
 		scriptFile = filepath.Join(GetExeDirectory(), scriptFile)
 	}
@@ -115,7 +133,12 @@ func runInitScript(scriptFile string, debug bool) {
 	cmdParts = append(cmdParts, scriptFile)
 
 	cmdStr := strings.Join(cmdParts, " ")
-	_, _ = CommandProcessor("", strings.NewReader(cmdStr), false, true)
+	p := newProcessor("", true)
+	p.process(strings.NewReader(cmdStr), "")
+	if IsFlowControl(p.exitErr, FlowExit) {
+		return p.exitErr
+	}
+	return nil
 }
 
 func runCmdLine(args []string) {
@@ -0,0 +1,34 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitInInitScriptEndsShell(t *testing.T) {
+	saved := DefaultInitFileName
+	defer func() { DefaultInitFileName = saved }()
+
+	DefaultInitFileName = writeTestScript(t, ".rsconfig", "record init\nexit 7\nrecord after\n")
+	os.WriteFile(DefaultInitFileName+DefaultInitFileExt, []byte("record user\n"), 0644)
+
+	runTestScript("")
+	err := runInitScripts(StartupOptions{})
+	if err == nil || GetExitCode(err) != 7 {
+		t.Errorf("expected the exit of the shell with code 7 but got %v", err)
+	}
+	verifyRecorded(t, testRecorder.lines, "init")
+
+	os.WriteFile(DefaultInitFileName, []byte("record init\nexit --script 3\n"), 0644)
+	runTestScript("")
+	if err := runInitScripts(StartupOptions{}); err != nil {
+		t.Errorf("expected only the script to end but got %v", err)
+	}
+	verifyRecorded(t, testRecorder.lines, "init", "user")
+
+	DefaultInitFileName = filepath.Join(filepath.Dir(DefaultInitFileName), "missing")
+	if err := runInitScripts(StartupOptions{}); err != nil {
+		t.Errorf("expected no exit without init scripts but got %v", err)
+	}
+}
@@ -22,6 +22,7 @@
 //  same cache data to ensure consistency for a given key.
 //
 //  A function is defined as: %%funcname([key, [fmt, [option]]])%%
+//  Functions can also be called from an expression: %%= funcname(key) %%
 //  When a function is parsed, the funcname is used to identify a function to
 //  call. The function is given any previous data returned from a function
 //  within a group (a group shares one cache item). Groups allow multiple data elements
@@ -110,6 +111,7 @@ func RegisterSubstitutionHandler(function SubstitutionFunction) {
 			fmt.Println("Registering:", function.Group, function.Name)
 		}
 		handlerMap[function.Name] = function
+		resetExpressionLanguage()
 	} else {
 		panic("Duplicate substitution registration: " + function.Group + "." + function.Name)
 	}
@@ -123,7 +125,11 @@ func GetSubstitutionFunction(name string) (fn SubstitutionFunction, ok bool) {
 // PerformVariableSubstitution -- perform substitution on a string
 func PerformVariableSubstitution(input string) string {
 
-	var localVars = buildSubstitutionFunctionVars(input)
+	// Expressions share keyed function data with the functions of the input
+	var cache = make(substitutionDataCache, 0)
+	input = performExpressionSubstitution(input, cache)
+
+	var localVars = buildSubstitutionFunctionVars(input, cache)
 
 	var replaceStrings = make([]string, 0)
 
@@ -213,8 +219,7 @@ func SortedGroupSubstitutionFunctionList(group string) []SubstitutionFunction {
 	return arr
 }
 
-func buildSubstitutionFunctionVars(input string) map[string]string {
-	var cache = make(substitutionDataCache, 0)
+func buildSubstitutionFunctionVars(input string, cache substitutionDataCache) map[string]string {
 	var localVars = make(map[string]string, 0)
 
 	pattern, _ := regexp.Compile(regexPattern)
@@ -250,22 +255,33 @@ func buildSubstitutionFunctionVars(input string) map[string]string {
 			option = list[4]
 		}
 
-		if r, ok := handlerMap[fn]; ok {
-			cachekey := r.Group + "__" + key
-			data, precached := cache[cachekey]
-			if !precached {
-				data = nil
-			}
-
-			if r.Function != nil {
-				if v, c := r.Function(data, fn, format, option); c != nil {
-					localVars[varName] = v
-					if data == nil {
-						cache[cachekey] = c
-					}
-				}
-			}
+		if v, ok := callSubstitutionFunction(cache, fn, key, format, option); ok {
+			localVars[varName] = v
 		}
 	}
 	return localVars
 }
+
+// callSubstitutionFunction -- call a registered function with the cached data
+// of its group for the key; returns false if the function provided no value
+func callSubstitutionFunction(cache substitutionDataCache, fn string, key string, format string, option string) (string, bool) {
+	r, ok := handlerMap[fn]
+	if !ok || r.Function == nil {
+		return "", false
+	}
+
+	cachekey := r.Group + "__" + key
+	data, precached := cache[cachekey]
+	if !precached {
+		data = nil
+	}
+
+	v, c := r.Function(data, fn, format, option)
+	if c == nil {
+		return "", false
+	}
+	if data == nil {
+		cache[cachekey] = c
+	}
+	return v, true
+}
@@ -0,0 +1,239 @@
+// Request timing
+//
+// HTTP requests record the duration of their phases with net/http/httptrace:
+// the DNS lookup, the TCP connect, the TLS handshake, the time to first byte
+// (from the connection being ready until the first byte of the response, the
+// time to send the request and the server processing) and the transfer of the
+// body. Phases of a reused connection are zero. Redirects add the phases of
+// each request; with retries only the last attempt is recorded.
+//
+// The --path-timing option references the timing of a result in milliseconds
+// with the paths dns, connect, tls, ttfb, transfer and total, and reused:
+//
+//	assert lt --path-timing ttfb 250
+
+package shell
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestTiming -- the duration of the phases of a request
+type RequestTiming struct {
+	DNS      time.Duration // DNS lookup
+	Connect  time.Duration // TCP connect
+	TLS      time.Duration // TLS handshake
+	TTFB     time.Duration // Connection ready until the first response byte
+	Transfer time.Duration // First response byte until the end of the body
+	Total    time.Duration // Start of the request until the end of the body
+	Reused   bool          // The request used an idle connection
+}
+
+// historyMap -- the timing in milliseconds as a history map
+func (t RequestTiming) historyMap() HistoryMap {
+	return &JsonMap{data: map[string]interface{}{
+		"dns":      durationInMs(t.DNS),
+		"connect":  durationInMs(t.Connect),
+		"tls":      durationInMs(t.TLS),
+		"ttfb":     durationInMs(t.TTFB),
+		"transfer": durationInMs(t.Transfer),
+		"total":    durationInMs(t.Total),
+		"reused":   t.Reused,
+	}}
+}
+
+// describe -- a line with the duration of the phases
+func (t RequestTiming) describe() string {
+	phases := []string{
+		"dns " + FormatMsTime(durationInMs(t.DNS)),
+		"connect " + FormatMsTime(durationInMs(t.Connect)),
+		"tls " + FormatMsTime(durationInMs(t.TLS)),
+		"ttfb " + FormatMsTime(durationInMs(t.TTFB)),
+		"transfer " + FormatMsTime(durationInMs(t.Transfer)),
+		"total " + FormatMsTime(durationInMs(t.Total)),
+	}
+	line := strings.Join(phases, ", ")
+	if t.Reused {
+		line = line + " (reused connection)"
+	}
+	return line
+}
+
+func durationInMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// requestTrace -- records the timing and number of attempts of a request
+type requestTrace struct {
+	mutex        sync.Mutex
+	attempts     int
+	timing       RequestTiming
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	gotConn      time.Time
+	firstByte    time.Time
+}
+
+// attempt -- start the timing of a new attempt; returns the context of the
+// request tracing the attempt
+func (t *requestTrace) attempt(ctx context.Context) context.Context {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.attempts++
+	t.timing = RequestTiming{}
+	t.start = time.Now()
+	t.gotConn = time.Time{}
+	t.firstByte = time.Time{}
+	return httptrace.WithClientTrace(ctx, t.clientTrace())
+}
+
+// finish -- end the timing when the body of the response was read
+func (t *requestTrace) finish() RequestTiming {
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.firstByte.IsZero() {
+		t.timing.Transfer = now.Sub(t.firstByte)
+	}
+	t.timing.Total = now.Sub(t.start)
+	return t.timing
+}
+
+// clientTrace -- the hooks of the phases; a dial may connect to several
+// addresses at once so only a successful connect is recorded
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(update func(now time.Time)) {
+		now := time.Now()
+		t.mutex.Lock()
+		update(now)
+		t.mutex.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func(now time.Time) { t.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func(now time.Time) {
+				if !t.dnsStart.IsZero() {
+					t.timing.DNS += now.Sub(t.dnsStart)
+					t.dnsStart = time.Time{}
+				}
+			})
+		},
+		ConnectStart: func(string, string) {
+			record(func(now time.Time) {
+				if t.connectStart.IsZero() {
+					t.connectStart = now
+				}
+			})
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			record(func(now time.Time) {
+				if err == nil && !t.connectStart.IsZero() {
+					t.timing.Connect += now.Sub(t.connectStart)
+					t.connectStart = time.Time{}
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			record(func(now time.Time) { t.tlsStart = now })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func(now time.Time) {
+				if !t.tlsStart.IsZero() {
+					t.timing.TLS += now.Sub(t.tlsStart)
+					t.tlsStart = time.Time{}
+				}
+			})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			record(func(now time.Time) {
+				t.gotConn = now
+				t.connectStart = time.Time{}
+				t.timing.Reused = info.Reused
+			})
+		},
+		GotFirstResponseByte: func() {
+			record(func(now time.Time) {
+				t.firstByte = now
+				if !t.gotConn.IsZero() {
+					t.timing.TTFB += now.Sub(t.gotConn)
+				}
+			})
+		},
+	}
+}
+
+// TimingSummary -- the average duration of the phases of requests
+type TimingSummary struct {
+	Count   int
+	Reused  int
+	average RequestTiming
+	total   RequestTiming
+}
+
+// Add -- add the timing of a request to the summary
+func (s *TimingSummary) Add(t RequestTiming) {
+	s.Count++
+	if t.Reused {
+		s.Reused++
+	}
+	s.total.DNS += t.DNS
+	s.total.Connect += t.Connect
+	s.total.TLS += t.TLS
+	s.total.TTFB += t.TTFB
+	s.total.Transfer += t.Transfer
+	s.total.Total += t.Total
+
+	n := time.Duration(s.Count)
+	s.average = RequestTiming{
+		DNS:      s.total.DNS / n,
+		Connect:  s.total.Connect / n,
+		TLS:      s.total.TLS / n,
+		TTFB:     s.total.TTFB / n,
+		Transfer: s.total.Transfer / n,
+		Total:    s.total.Total / n,
+	}
+}
+
+// Average -- the average duration of the phases
+func (s *TimingSummary) Average() RequestTiming {
+	return s.average
+}
+
+// describe -- a line with the average phases and the connections reused
+func (s *TimingSummary) describe() string {
+	return fmt.Sprintf("%s (%d of %d connections reused)", s.average.describe(), s.Reused, s.Count)
+}
+
+// timingCsvHeadings -- the CSV columns of a timing summary
+var timingCsvHeadings = []string{"DNS", "Connect", "TLS", "TTFB", "Transfer", "Reused"}
+
+// csvFields -- the CSV columns of the average phases in milliseconds (or
+// formatted) and the connections reused; empty without timings
+func (s *TimingSummary) csvFields(formatted bool) []string {
+	fields := make([]string, len(timingCsvHeadings))
+	if s.Count == 0 {
+		return fields
+	}
+	for i, d := range []time.Duration{s.average.DNS, s.average.Connect, s.average.TLS, s.average.TTFB, s.average.Transfer} {
+		ms := float64(d) / float64(time.Millisecond)
+		if formatted {
+			fields[i] = FormatMsTime(ms)
+		} else {
+			fields[i] = fmt.Sprintf("%f", ms)
+		}
+	}
+	fields[len(fields)-1] = strconv.Itoa(s.Reused)
+	return fields
+}
@@ -0,0 +1,132 @@
+package shell
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSlowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestTimingPhases(t *testing.T) {
+	server := newSlowServer(t, 20*time.Millisecond)
+	client := NewRestClient()
+
+	resp, err := client.DoMethod(http.MethodGet, nil, server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	timing := resp.GetTiming()
+	if timing.Reused || timing.Connect <= 0 || timing.DNS != 0 || timing.TLS != 0 {
+		t.Errorf("unexpected phases of a new connection: %v", timing)
+	}
+	if timing.TTFB < 20*time.Millisecond || timing.Total < timing.Connect+timing.TTFB+timing.Transfer {
+		t.Errorf("expected the server delay in the time to first byte: %v", timing)
+	}
+
+	resp, _ = client.DoMethod(http.MethodGet, nil, server.URL)
+	if timing := resp.GetTiming(); !timing.Reused || timing.Connect != 0 {
+		t.Errorf("expected a reused connection without connect: %v", timing)
+	}
+}
+
+func TestRequestTimingTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := NewRestClient()
+	client.Client = server.Client()
+	resp, err := client.DoMethod(http.MethodGet, nil, server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	if timing := resp.GetTiming(); timing.TLS <= 0 {
+		t.Errorf("expected the TLS handshake to be recorded: %v", timing)
+	}
+}
+
+func TestTimingPathOfHistory(t *testing.T) {
+	server := newSlowServer(t, 10*time.Millisecond)
+	client := NewRestClient()
+	resp, err := client.DoMethod(http.MethodGet, nil, server.URL)
+	PushResponse(resp, err)
+
+	set := NewCmdSet()
+	options := AddHistoryOptions(set, AlternatePaths)
+	CmdParse(set, []string{"assert", "--path-timing"})
+	for path, minimum := range map[string]string{"ttfb": "10", "total": "10", "dns": "0", "reused": "false"} {
+		node, err := options.GetNodeFromHistory(0, path)
+		if err != nil {
+			t.Errorf("unable to get %s: %s", path, err.Error())
+			continue
+		}
+		if cmp, err := CompareNodeValue(node, minimum); err != nil || cmp < 0 {
+			t.Errorf("expected %s to be at least %s but got %v", path, minimum, node)
+		}
+	}
+
+	result, _ := PeekResult(0)
+	states := captureBranchState().History
+	if restored := states[len(states)-1].toResult(); restored.Timing == nil || *restored.Timing != *result.Timing {
+		t.Errorf("expected the timing to be kept by the branch state")
+	}
+
+	PushText("text/plain", "text", nil)
+	if _, err := options.GetNodeFromHistory(0, "ttfb"); err != ErrNotFound {
+		t.Errorf("expected no timing for a text result but got %v", err)
+	}
+}
+
+func TestBenchmarkTimingAverage(t *testing.T) {
+	bm := NewBenchmark(3)
+	for k, ttfb := range []time.Duration{10, 20, 60} {
+		bm.Iterations[k].SetTiming(RequestTiming{Connect: time.Millisecond, TTFB: ttfb * time.Millisecond, Reused: k > 0})
+		bm.Iterations[k].WallTime = int64(ttfb * time.Millisecond)
+	}
+	bm.Iterations[2].Err = http.ErrHandlerTimeout
+
+	summary := bm.TimingAverage()
+	if summary.Count != 2 || summary.Reused != 1 || summary.Average().TTFB != 15*time.Millisecond ||
+		summary.Average().Connect != time.Millisecond {
+		t.Errorf("unexpected average of the successful iterations: %v", summary)
+	}
+
+	var output bytes.Buffer
+	savedOutput := currentOutput
+	currentOutput = &output
+	defer func() { currentOutput = savedOutput }()
+
+	csv, formatted := true, false
+	opts := StandardOptions{csvOutputOption: &csv, prettyCsvOption: &formatted}
+	bm.Dump("phases", opts, false)
+	lines := strings.Split(output.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Label,") || !strings.HasSuffix(lines[0], ",Message,DNS,Connect,TLS,TTFB,Transfer,Reused,") ||
+		!strings.HasSuffix(lines[1], ",0.000000,1.000000,0.000000,15.000000,0.000000,1") {
+		t.Errorf("expected the average phases in CSV columns:\n%s", output.String())
+	}
+
+	output.Reset()
+	formatted = true
+	bm.Dump("phases", opts, false)
+	if lines := strings.Split(output.String(), "\n"); !strings.HasSuffix(lines[1], ",0ms,1.000ms,0ms,15.000ms,0ms,1") {
+		t.Errorf("expected formatted phases in CSV columns:\n%s", output.String())
+	}
+
+	output.Reset()
+	csv, formatted = false, false
+	bm.Dump("phases", opts, false)
+	if !strings.Contains(output.String(), "Phases (avg)") {
+		t.Errorf("expected the average phases line:\n%s", output.String())
+	}
+}
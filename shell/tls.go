@@ -0,0 +1,189 @@
+// TLS
+//
+// The TLS configuration of a client combines the options of the command with
+// the TLS variables; an option overrides its variable:
+//
+//	--client-cert   .config.restshell.tls.cert      client certificate (PEM or PKCS#12)
+//	--client-key    .config.restshell.tls.key       private key of a PEM certificate
+//	--client-password .config.restshell.tls.password password of a PKCS#12 certificate
+//	--ca-bundle     .config.restshell.tls.cabundle  trusted CA certificates (PEM)
+//	--tls-min       .config.restshell.tls.min       minimum TLS version
+//	--sni           .config.restshell.tls.sni       server name of the handshake
+//
+// A CA bundle replaces the system certificates; --certs adds the cert.pem of
+// the init or exe directory to the system certificates or the bundle and
+// --nocert skips the verification of the server certificate.
+
+package shell
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// Variables of the TLS settings
+var (
+	TLSCertVariable       = ".config.restshell.tls.cert"
+	TLSKeyVariable        = ".config.restshell.tls.key"
+	TLSPasswordVariable   = ".config.restshell.tls.password"
+	TLSCABundleVariable   = ".config.restshell.tls.cabundle"
+	TLSMinVersionVariable = ".config.restshell.tls.min"
+	TLSServerNameVariable = ".config.restshell.tls.sni"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSSettings -- the TLS settings of a client
+type TLSSettings struct {
+	ClientCert     string // PEM or PKCS#12 (.p12 or .pfx) file
+	ClientKey      string // PEM file of the key; the certificate file when empty
+	ClientPassword string // Password of a PKCS#12 file
+	CABundle       string // PEM file of the trusted CA certificates
+	MinVersion     string // 1.0, 1.1, 1.2 or 1.3
+	ServerName     string // Server name sent and verified (SNI)
+	LocalCerts     bool   // Trust the cert.pem of the init or exe directory
+	SkipVerify     bool   // Do not verify the server certificate
+}
+
+// GetCmdTLSSettings -- the TLS settings of the command options and the
+// TLS variables
+func GetCmdTLSSettings() TLSSettings {
+	return globalOptions.GetTLSSettings()
+}
+
+// GetTLSSettings -- the TLS settings of the options and the TLS variables
+func (o *StandardOptions) GetTLSSettings() TLSSettings {
+	return TLSSettings{
+		ClientCert:     getOptionOrVariable(o.clientCertOption, TLSCertVariable),
+		ClientKey:      getOptionOrVariable(o.clientKeyOption, TLSKeyVariable),
+		ClientPassword: getOptionOrVariable(o.clientPasswordOption, TLSPasswordVariable),
+		CABundle:       getOptionOrVariable(o.caBundleOption, TLSCABundleVariable),
+		MinVersion:     getOptionOrVariable(o.tlsMinOption, TLSMinVersionVariable),
+		ServerName:     getOptionOrVariable(o.sniOption, TLSServerNameVariable),
+		LocalCerts:     o.IsLocalCertsEnabled(),
+		SkipVerify:     o.IsSkipCertValidationEnabled(),
+	}
+}
+
+func getOptionOrVariable(option *string, variable string) string {
+	if option != nil && len(*option) > 0 {
+		return *option
+	}
+	return GetGlobalString(variable)
+}
+
+// IsDefault -- true when the settings do not change the default TLS
+// configuration
+func (s TLSSettings) IsDefault() bool {
+	return s == TLSSettings{}
+}
+
+// NewTLSConfig -- build the TLS configuration of the settings; returns nil
+// for the default settings
+func NewTLSConfig(s TLSSettings) (*tls.Config, error) {
+	if s.IsDefault() {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         s.ServerName,
+		InsecureSkipVerify: s.SkipVerify,
+	}
+
+	if len(s.MinVersion) > 0 {
+		version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(s.MinVersion), "tls")]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLS version: %s", s.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if len(s.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		data, err := os.ReadFile(s.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %s", err.Error())
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA bundle: %s", s.CABundle)
+		}
+		if s.LocalCerts {
+			appendLocalCertificate(pool)
+		}
+		config.RootCAs = pool
+	} else if s.LocalCerts {
+		config.RootCAs = GetX509Pool()
+	}
+
+	if len(s.ClientCert) > 0 {
+		cert, err := loadClientCertificate(s.ClientCert, s.ClientKey, s.ClientPassword)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if len(s.ClientKey) > 0 {
+		return nil, errors.New("a client key requires a client certificate")
+	}
+	return config, nil
+}
+
+// loadClientCertificate -- load a PEM certificate and key or a PKCS#12 file
+func loadClientCertificate(certFile string, keyFile string, password string) (tls.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to read client certificate: %s", err.Error())
+	}
+
+	if isPKCS12(certFile, data) {
+		if len(keyFile) > 0 {
+			return tls.Certificate{}, errors.New("a PKCS#12 client certificate contains the key")
+		}
+		blocks, err := pkcs12.ToPEM(data, password)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("unable to decode client certificate: %s", err.Error())
+		}
+		var pemData []byte
+		for _, block := range blocks {
+			pemData = append(pemData, pem.EncodeToMemory(block)...)
+		}
+		cert, err := tls.X509KeyPair(pemData, pemData)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("invalid client certificate: %s", err.Error())
+		}
+		return cert, nil
+	}
+
+	keyData := data
+	if len(keyFile) > 0 {
+		if keyData, err = os.ReadFile(keyFile); err != nil {
+			return tls.Certificate{}, fmt.Errorf("unable to read client key: %s", err.Error())
+		}
+	}
+	cert, err := tls.X509KeyPair(data, keyData)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid client certificate: %s", err.Error())
+	}
+	return cert, nil
+}
+
+// isPKCS12 -- true for .p12 and .pfx files or files that are not PEM
+func isPKCS12(file string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".p12", ".pfx":
+		return true
+	}
+	return !strings.Contains(string(data), "-----BEGIN")
+}
@@ -0,0 +1,219 @@
+package shell
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	golog "log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate -- a certificate and key signed by a parent (or self
+// signed) written to PEM files
+type testCertificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, template x509.Certificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err.Error())
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.Subject = pkix.Name{CommonName: name}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := &template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %s", err.Error())
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+
+	dir := t.TempDir()
+	tc := &testCertificate{cert: cert, key: key,
+		certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+".key")}
+	os.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return tc
+}
+
+func (tc *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{tc.cert.Raw}, PrivateKey: tc.key}
+}
+
+// newMutualTLSServer -- a server for books.internal requiring a client
+// certificate signed by the CA
+func newMutualTLSServer(t *testing.T) (*httptest.Server, *testCertificate, *testCertificate) {
+	ca := newTestCertificate(t, "ca", nil, x509.Certificate{IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign})
+	server := newTestCertificate(t, "server", ca, x509.Certificate{DNSNames: []string{"books.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	client := newTestCertificate(t, "client", ca, x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName + " " + r.TLS.ServerName))
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MaxVersion:   tls.VersionTLS12,
+	}
+	ts.Config.ErrorLog = golog.New(io.Discard, "", 0)
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts, ca, client
+}
+
+func doTLSRequest(t *testing.T, settings TLSSettings, url string) (*RestResponse, error) {
+	t.Helper()
+	config, err := NewTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	client := NewRestClient()
+	client.Client.Transport = transport
+	return client.DoMethod(http.MethodGet, nil, url)
+}
+
+func TestMutualTLSWithCABundleAndServerName(t *testing.T) {
+	server, ca, client := newMutualTLSServer(t)
+	settings := TLSSettings{ClientCert: client.certFile, ClientKey: client.keyFile,
+		CABundle: ca.certFile, ServerName: "books.internal"}
+
+	resp, err := doTLSRequest(t, settings, server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	if resp.Text != "client books.internal" {
+		t.Errorf("unexpected response: %s", resp.Text)
+	}
+
+	settings.ServerName = ""
+	if _, err := doTLSRequest(t, settings, server.URL); err == nil {
+		t.Errorf("expected the server name of the address to fail verification")
+	}
+
+	settings.ServerName = "books.internal"
+	settings.MinVersion = "1.3"
+	if _, err := doTLSRequest(t, settings, server.URL); err == nil {
+		t.Errorf("expected the minimum version to fail the handshake")
+	}
+
+	if _, err := doTLSRequest(t, TLSSettings{SkipVerify: true}, server.URL); err == nil {
+		t.Errorf("expected the request without a client certificate to fail")
+	}
+	resp, err = doTLSRequest(t, TLSSettings{SkipVerify: true, ClientCert: client.certFile, ClientKey: client.keyFile}, server.URL)
+	if err != nil || !strings.HasPrefix(resp.Text, "client") {
+		t.Errorf("expected the client certificate without verification to succeed: %v", err)
+	}
+}
+
+func TestReplacedCertificateFileGetsNewTransport(t *testing.T) {
+	template := x509.Certificate{IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}
+	first := newTestCertificate(t, "first", nil, template)
+	second := newTestCertificate(t, "second", nil, template)
+	settings := TLSSettings{CABundle: first.certFile}
+
+	transport, err := getCachedTransport(settings, ProxyDirect, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if cached, _ := getCachedTransport(settings, ProxyDirect, ""); cached != transport {
+		t.Errorf("expected the transport of the settings to be shared")
+	}
+
+	data, _ := os.ReadFile(second.certFile)
+	os.WriteFile(first.certFile, data, 0600)
+	modified := time.Now().Add(time.Minute)
+	os.Chtimes(first.certFile, modified, modified)
+	replaced, err := getCachedTransport(settings, ProxyDirect, "")
+	if err != nil || replaced == transport {
+		t.Fatalf("expected a new transport for the replaced CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(second.cert)
+	if !replaced.TLSClientConfig.RootCAs.Equal(pool) {
+		t.Errorf("expected the transport to trust the replaced CA bundle")
+	}
+	if cached, _ := getCachedTransport(settings, ProxyDirect, ""); cached != replaced {
+		t.Errorf("expected the new transport to be shared")
+	}
+}
+
+func TestTLSSettingsFromVariables(t *testing.T) {
+	_, ca, client := newMutualTLSServer(t)
+	SetGlobal(TLSCertVariable, client.certFile)
+	SetGlobal(TLSKeyVariable, client.keyFile)
+	SetGlobal(TLSCABundleVariable, ca.certFile)
+	defer func() {
+		RemoveGlobal(TLSCertVariable)
+		RemoveGlobal(TLSKeyVariable)
+		RemoveGlobal(TLSCABundleVariable)
+	}()
+
+	settings := GetCmdTLSSettings()
+	if settings.ClientCert != client.certFile || settings.ClientKey != client.keyFile || settings.CABundle != ca.certFile {
+		t.Errorf("unexpected settings: %v", settings)
+	}
+	if rc := NewRestClientFromOptions(); rc.configErr != nil || rc.Client.Transport.(*http.Transport).TLSClientConfig == nil {
+		t.Errorf("expected the client to use the TLS configuration: %v", rc.configErr)
+	}
+
+	SetGlobal(TLSKeyVariable, ca.certFile)
+	rc := NewRestClientFromOptions()
+	if _, err := rc.DoMethod(http.MethodGet, nil, "https://"+net.JoinHostPort("127.0.0.1", "1")); err == nil ||
+		!strings.Contains(err.Error(), "invalid client certificate") {
+		t.Errorf("expected the requests to fail with the TLS error but got %v", err)
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(file, []byte("-----BEGIN NOTHING-----\n"), 0600)
+
+	for _, settings := range []TLSSettings{
+		{MinVersion: "1.4"},
+		{CABundle: file},
+		{CABundle: file + ".missing"},
+		{ClientKey: file},
+		{ClientCert: file, ClientPassword: "secret"},
+	} {
+		if _, err := NewTLSConfig(settings); err == nil {
+			t.Errorf("expected an error for %v", settings)
+		}
+	}
+	if config, err := NewTLSConfig(TLSSettings{}); config != nil || err != nil {
+		t.Errorf("expected no configuration for the default settings")
+	}
+}
@@ -0,0 +1,133 @@
+package shell
+
+import (
+	"errors"
+)
+
+// Variables set for the CATCH clause of a TRY block
+var (
+	ErrorMessageVariable  = "$error.message"
+	ErrorCommandVariable  = "$error.command"
+	ErrorLocationVariable = "$error.location"
+)
+
+// trapDepth -- number of TRY blocks executing; while executing, command errors
+// are trapped to stop the executing blocks instead of being reported
+var trapDepth = 0
+
+// CommandError -- an error returned by a command trapped for a TRY block
+type CommandError struct {
+	Command  string
+	Location string // file:line of the command when executed from a script
+	Err      error
+}
+
+func newCommandError(command string, err error, location string) error {
+	if cmdErr, ok := err.(CommandError); ok {
+		return cmdErr
+	}
+	return CommandError{Command: command, Location: location, Err: err}
+}
+
+// Error -- the message of the command error
+func (e CommandError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap -- the error returned by the command
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
+
+// executeTryBlock -- execute the TRY clause trapping command errors and flow
+// aborts (except user interrupts). A trapped error executes the CATCH clause
+// and the FINALLY clause always executes. Without a CATCH clause the trapped
+// error is reported after the FINALLY clause.
+//
+//	TRY
+//	  ...
+//	CATCH
+//	  ... %%$error.message%% %%$error.command%% %%$error.location%%
+//	FINALLY
+//	  ...
+//	ENDTRY
+func executeTryBlock(p *processor, block *controlBlock) error {
+	catch, finally, err := getTryClauses(block)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.prepareClause(block.clauses[0]); err != nil {
+		return err
+	}
+
+	trapDepth++
+	p.executeStatements(block.clauses[0].body)
+	trapDepth--
+
+	trapped := p.trapped
+	p.trapped = nil
+	if trapped != nil && catch != nil {
+		if _, err := p.prepareClause(*catch); err != nil {
+			return err
+		}
+
+		command, location := "", ""
+		if cmdErr, ok := trapped.(CommandError); ok {
+			command, location = cmdErr.Command, cmdErr.Location
+		}
+		SetGlobal(ErrorMessageVariable, trapped.Error())
+		SetGlobal(ErrorCommandVariable, command)
+		SetGlobal(ErrorLocationVariable, location)
+		trapped = nil
+		p.executeStatements(catch.body)
+	}
+
+	if finally != nil {
+		if err := p.executeFinally(*finally); err != nil {
+			return err
+		}
+	}
+	return trapped
+}
+
+// getTryClauses -- validate the clauses of a TRY block returning the CATCH
+// and FINALLY clauses (nil if not present)
+func getTryClauses(block *controlBlock) (catch *blockClause, finally *blockClause, err error) {
+	for i := 1; i < len(block.clauses); i++ {
+		clause := &block.clauses[i]
+		switch {
+		case clause.keyword == "CATCH" && catch == nil && finally == nil:
+			catch = clause
+		case clause.keyword == "FINALLY" && finally == nil:
+			finally = clause
+		default:
+			return nil, nil, errors.New("TRY blocks support one CATCH followed by one FINALLY clause")
+		}
+	}
+	if catch == nil && finally == nil {
+		return nil, nil, errors.New("TRY block requires a CATCH or FINALLY clause")
+	}
+	return catch, finally, nil
+}
+
+// executeFinally -- execute a FINALLY clause regardless of the state of the
+// processor and then restore the state unless changed by the clause
+func (p *processor) executeFinally(finally blockClause) error {
+	quit, interrupted, returned, trapped := p.quit, p.interrupted, p.returned, p.trapped
+	p.quit, p.interrupted, p.returned, p.trapped = false, false, false, nil
+	defer func() {
+		p.quit = p.quit || quit
+		p.interrupted = p.interrupted || interrupted
+		p.returned = p.returned || returned
+		if p.trapped == nil {
+			p.trapped = trapped
+		}
+	}()
+
+	if _, err := p.prepareClause(finally); err != nil {
+		return err
+	}
+	p.executeStatements(finally.body)
+	return nil
+}
@@ -0,0 +1,124 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryCatchesCommandErrors(t *testing.T) {
+	script := `
+TRY
+  record one
+  record fail
+  record never
+CATCH
+  record caught %%$error.command%% %%$error.message%%
+FINALLY
+  record finally
+ENDTRY
+record after
+`
+	verifyRecorded(t, runTestScript(script), "one", "caught RECORD record failed", "finally", "after")
+}
+
+func TestTryWithoutErrorSkipsCatch(t *testing.T) {
+	script := `
+TRY
+  record one
+CATCH
+  record caught
+FINALLY
+  record finally
+ENDTRY
+`
+	verifyRecorded(t, runTestScript(script), "one", "finally")
+	if LastError != 0 {
+		t.Errorf("unexpected error state")
+	}
+}
+
+func TestTryCatchesFlowAbort(t *testing.T) {
+	script := `
+TRY
+  record abort
+CATCH
+  record caught %%$error.message%%
+ENDTRY
+record after
+`
+	verifyRecorded(t, runTestScript(script), "caught record aborted", "after")
+}
+
+func TestTryFinallyWithoutCatchReportsError(t *testing.T) {
+	script := `
+TRY
+  TRY
+    record fail
+    record never
+  FINALLY
+    record inner finally
+  ENDTRY
+  record never
+CATCH
+  record outer %%$error.command%%
+ENDTRY
+`
+	verifyRecorded(t, runTestScript(script), "inner finally", "outer RECORD")
+}
+
+func TestTryCatchesErrorsInProceduresAndScripts(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "fail.rshell")
+	os.WriteFile(script, []byte("record script\nrecord fail\nrecord never\n"), 0644)
+
+	verifyRecorded(t, runTestScript(`
+PROC failing
+  record proc
+  bogus command
+  record never
+ENDPROC
+TRY
+  failing
+CATCH
+  record caught %%$error.command%%
+ENDTRY
+TRY
+  run -s `+script+`
+CATCH
+  record caught %%$error.command%%
+ENDTRY
+`), "proc", "caught BOGUS", "script", "caught RECORD")
+}
+
+func TestTryFinallyRunsOnReturn(t *testing.T) {
+	script := `
+PROC cleanup
+  TRY
+    RETURN done
+  FINALLY
+    record finally
+  ENDTRY
+  record never
+ENDPROC
+cleanup
+record %%$return%%
+`
+	verifyRecorded(t, runTestScript(script), "finally", "done")
+}
+
+func TestTryClauseOrder(t *testing.T) {
+	script := `
+TRY
+  record try
+FINALLY
+  record finally
+CATCH
+  record catch
+ENDTRY
+`
+	verifyRecorded(t, runTestScript(script))
+	if LastError == 0 {
+		t.Errorf("expected an error for CATCH after FINALLY")
+	}
+}
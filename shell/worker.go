@@ -58,6 +58,9 @@ func (w worker) Process(jobs <-chan int, endTime *time.Time, cancelPtr *bool) {
 
 		resp, err := w.invoke(processor)
 		context.EndIteration(err)
+		if tc, ok := context.(TimingContext); ok && err == nil && resp != nil {
+			tc.SetTiming(resp.GetTiming())
+		}
 		if err == nil {
 			if w.completion != nil {
 				w.completion(job, context, resp)